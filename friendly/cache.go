@@ -0,0 +1,241 @@
+package friendly
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCacheNotFound loader 返回该错误（或 WithCacheIsNotFound 判定为不存在的错误）时，结果会被负缓存
+var ErrCacheNotFound = errors.New("cache: not found")
+
+// cacheNegativeValue 负缓存在 Redis 中的占位值，不会与 codec 的输出冲突
+const cacheNegativeValue = "\x00nil"
+
+type cacheOptions struct {
+	ttl         time.Duration
+	jitter      time.Duration
+	negativeTTL time.Duration
+	localSize   int
+	localTTL    time.Duration
+	isNotFound  func(error) bool
+	logger      log.Logger
+}
+
+// CacheOption Cache 的可选参数
+type CacheOption func(o *cacheOptions)
+
+// WithCacheTTL Redis 中的缓存时间，默认 10m
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) { o.ttl = ttl }
+}
+
+// WithCacheJitter 在 TTL 上叠加 [0, jitter) 的随机时长，避免同批 key 同时过期，默认 ttl/10
+func WithCacheJitter(jitter time.Duration) CacheOption {
+	return func(o *cacheOptions) { o.jitter = jitter }
+}
+
+// WithCacheNegativeTTL 不存在结果的缓存时间，默认 1m；<=0 时不做负缓存
+func WithCacheNegativeTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) { o.negativeTTL = ttl }
+}
+
+// WithCacheLocal 启用进程内 LRU 一级缓存，size 为最大条目数，ttl 为本地条目有效期（应明显短于 Redis TTL）
+func WithCacheLocal(size int, ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) { o.localSize, o.localTTL = size, ttl }
+}
+
+// WithCacheIsNotFound 自定义“不存在”错误的判定（如 gorm.ErrRecordNotFound），默认只识别 ErrCacheNotFound
+func WithCacheIsNotFound(fn func(error) bool) CacheOption {
+	return func(o *cacheOptions) { o.isNotFound = fn }
+}
+
+// WithCacheLogger 日志输出，默认 log.GetLogger()
+func WithCacheLogger(logger log.Logger) CacheOption {
+	return func(o *cacheOptions) { o.logger = logger }
+}
+
+// localItem 本地缓存条目；notFound 表示负缓存
+type localItem[V any] struct {
+	val      V
+	notFound bool
+}
+
+// Cache 基于 Redis 的类型化旁路缓存（cache-aside）：
+// - GetOrLoad：本地 LRU（可选）→ Redis → loader，回源结果写回 Redis 与本地
+// - 同一 key 的并发回源经 singleflight 合并为一次；共享的 V 为同一份值，指针类型请勿修改
+// - 不存在的结果做负缓存，防止穿透
+// - Redis 读写失败时记录日志并直接回源，缓存不可用不影响读取
+// - 序列化使用传入的 codec，通常为 kratosx.Codec（proto.Message 走 protojson，其余走 json）
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+type Cache[K comparable, V any] struct {
+	rdb    redis.Cmdable
+	codec  encoding.Codec
+	prefix string
+	opts   cacheOptions
+	local  *lruCache[K, localItem[V]]
+	group  singleflight.Group
+	logger *log.Helper
+}
+
+// NewCache 创建缓存，Redis key 为 prefix + ":" + fmt.Sprint(key)
+func NewCache[K comparable, V any](rdb redis.Cmdable, codec encoding.Codec, prefix string, opts ...CacheOption) *Cache[K, V] {
+	o := cacheOptions{
+		ttl:         10 * time.Minute,
+		jitter:      -1,
+		negativeTTL: time.Minute,
+		logger:      log.GetLogger(),
+	}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if o.jitter < 0 {
+		o.jitter = o.ttl / 10
+	}
+	c := &Cache[K, V]{
+		rdb:    rdb,
+		codec:  codec,
+		prefix: prefix,
+		opts:   o,
+		logger: log.NewHelper(log.With(o.logger, "module", "cache/redis")),
+	}
+	if o.localSize > 0 && o.localTTL > 0 {
+		c.local = newLRUCache[K, localItem[V]](o.localSize)
+	}
+	return c
+}
+
+// Key 返回 key 对应的 Redis key
+func (c *Cache[K, V]) Key(key K) string {
+	return c.prefix + ":" + fmt.Sprint(key)
+}
+
+// GetOrLoad 读取缓存，未命中时调用 loader 回源并写回；不存在时返回 ErrCacheNotFound
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error)) (V, error) {
+	var zero V
+	if c.local != nil {
+		if it, ok := c.local.get(key); ok {
+			if it.notFound {
+				return zero, ErrCacheNotFound
+			}
+			return it.val, nil
+		}
+	}
+
+	rk := c.Key(key)
+	// 回源与调用方 ctx 解绑：单个调用方取消不应让合并在一起的其他调用方失败
+	ch := c.group.DoChan(rk, func() (interface{}, error) {
+		return c.load(context.WithoutCancel(ctx), key, rk, loader)
+	})
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		// V 为接口类型且 loader 返回 nil 时断言失败，得到零值
+		v, _ := res.Val.(V)
+		return v, nil
+	}
+}
+
+func (c *Cache[K, V]) load(ctx context.Context, key K, rk string, loader func(context.Context, K) (V, error)) (V, error) {
+	var zero V
+
+	bs, err := c.rdb.Get(ctx, rk).Bytes()
+	switch {
+	case err == nil:
+		if string(bs) == cacheNegativeValue {
+			c.setLocal(key, localItem[V]{notFound: true})
+			return zero, ErrCacheNotFound
+		}
+		var v V
+		if err := c.codec.Unmarshal(bs, &v); err == nil {
+			c.setLocal(key, localItem[V]{val: v})
+			return v, nil
+		}
+		// 反序列化失败（如结构变更）按未命中处理，回源后覆盖
+	case !errors.Is(err, redis.Nil):
+		c.logger.WithContext(ctx).Warnf("cache get failed, load from source: key=%s err=%v", rk, err)
+	}
+
+	v, err := loader(ctx, key)
+	if err != nil {
+		if c.isNotFound(err) {
+			if c.opts.negativeTTL > 0 {
+				_ = c.rdb.Set(ctx, rk, cacheNegativeValue, c.opts.negativeTTL).Err()
+				c.setLocal(key, localItem[V]{notFound: true})
+			}
+			return zero, ErrCacheNotFound
+		}
+		return zero, err
+	}
+
+	if err := c.Set(ctx, key, v); err != nil {
+		c.logger.WithContext(ctx).Warnf("cache set failed: key=%s err=%v", rk, err)
+	}
+	return v, nil
+}
+
+// Set 写入缓存（Redis + 本地）
+func (c *Cache[K, V]) Set(ctx context.Context, key K, v V) error {
+	bs, err := c.codec.Marshal(v)
+	if err != nil {
+		return errors.WithMessage(err, "cache marshal")
+	}
+	if err := c.rdb.Set(ctx, c.Key(key), bs, c.jitteredTTL()).Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	c.setLocal(key, localItem[V]{val: v})
+	return nil
+}
+
+// Delete 删除缓存（Redis + 本地），数据变更后调用以失效旧值。
+// 本地 LRU 只能清理本实例，其他实例的本地条目会在 local ttl 后过期。
+func (c *Cache[K, V]) Delete(ctx context.Context, keys ...K) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	rks := make([]string, 0, len(keys))
+	for _, k := range keys {
+		rks = append(rks, c.Key(k))
+		if c.local != nil {
+			c.local.remove(k)
+		}
+	}
+	// 逐个删除，集群模式下多 key DEL 可能跨 slot
+	for _, rk := range rks {
+		if err := c.rdb.Del(ctx, rk).Err(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (c *Cache[K, V]) setLocal(key K, it localItem[V]) {
+	if c.local != nil {
+		c.local.set(key, it, c.opts.localTTL)
+	}
+}
+
+func (c *Cache[K, V]) isNotFound(err error) bool {
+	if errors.Is(err, ErrCacheNotFound) {
+		return true
+	}
+	return c.opts.isNotFound != nil && c.opts.isNotFound(err)
+}
+
+func (c *Cache[K, V]) jitteredTTL() time.Duration {
+	if c.opts.jitter <= 0 {
+		return c.opts.ttl
+	}
+	return c.opts.ttl + rand.N(c.opts.jitter) //nolint:gosec
+}
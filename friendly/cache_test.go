@@ -0,0 +1,239 @@
+package friendly
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+type cacheUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newUnreachableCache(opts ...CacheOption) *Cache[int, cacheUser] {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	return NewCache[int, cacheUser](rdb, encoding.GetCodec("json"), "user", opts...)
+}
+
+func TestCacheKey(t *testing.T) {
+	c := newUnreachableCache()
+	if got := c.Key(42); got != "user:42" {
+		t.Fatalf("key=%q", got)
+	}
+}
+
+func TestCacheLocalTierHit(t *testing.T) {
+	c := newUnreachableCache(WithCacheLocal(16, time.Minute))
+	c.setLocal(1, localItem[cacheUser]{val: cacheUser{ID: 1, Name: "a"}})
+	c.setLocal(2, localItem[cacheUser]{notFound: true})
+
+	loader := func(context.Context, int) (cacheUser, error) {
+		t.Fatal("loader should not be called on local hit")
+		return cacheUser{}, nil
+	}
+	v, err := c.GetOrLoad(context.Background(), 1, loader)
+	if err != nil || v.Name != "a" {
+		t.Fatalf("v=%+v err=%v", v, err)
+	}
+	if _, err := c.GetOrLoad(context.Background(), 2, loader); !errors.Is(err, ErrCacheNotFound) {
+		t.Fatalf("negative local entry should return ErrCacheNotFound, got %v", err)
+	}
+}
+
+func TestCacheRedisErrorFallsBackToLoader(t *testing.T) {
+	c := newUnreachableCache()
+	called := false
+	v, err := c.GetOrLoad(context.Background(), 1, func(_ context.Context, id int) (cacheUser, error) {
+		called = true
+		return cacheUser{ID: id, Name: "a"}, nil
+	})
+	if err != nil || v.Name != "a" {
+		t.Fatalf("v=%+v err=%v", v, err)
+	}
+	if !called {
+		t.Fatal("loader should run when redis is unavailable")
+	}
+}
+
+func TestCacheIsNotFound(t *testing.T) {
+	errMissing := errors.New("record not found")
+	c := newUnreachableCache(WithCacheIsNotFound(func(err error) bool { return errors.Is(err, errMissing) }))
+	if !c.isNotFound(errors.WithStack(ErrCacheNotFound)) {
+		t.Fatal("wrapped ErrCacheNotFound should be not-found")
+	}
+	if !c.isNotFound(errors.WithMessage(errMissing, "query")) {
+		t.Fatal("custom not-found should be recognized")
+	}
+	if c.isNotFound(errors.New("boom")) {
+		t.Fatal("other errors should not be not-found")
+	}
+}
+
+func TestCacheJitteredTTL(t *testing.T) {
+	c := newUnreachableCache(WithCacheTTL(time.Minute))
+	for i := 0; i < 100; i++ {
+		d := c.jitteredTTL()
+		if d < time.Minute || d >= time.Minute+6*time.Second {
+			t.Fatalf("ttl %v out of range", d)
+		}
+	}
+	c = newUnreachableCache(WithCacheTTL(time.Minute), WithCacheJitter(0))
+	if d := c.jitteredTTL(); d != time.Minute {
+		t.Fatalf("ttl without jitter=%v", d)
+	}
+}
+
+func TestCacheInterfaceNilValue(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	c := NewCache[string, any](rdb, encoding.GetCodec("json"), "any")
+	v, err := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (any, error) {
+		return nil, nil
+	})
+	if err != nil || v != nil {
+		t.Fatalf("v=%v err=%v", v, err)
+	}
+}
+
+func newTestCache(t *testing.T, opts ...CacheOption) (*Cache[int, cacheUser], *miniredis.Miniredis) {
+	t.Helper()
+	rdb, mr := newTestRedis(t)
+	return NewCache[int, cacheUser](rdb, encoding.GetCodec("json"), "user", opts...), mr
+}
+
+func TestCacheRedisHit(t *testing.T) {
+	c, mr := newTestCache(t)
+	if err := c.Set(context.Background(), 1, cacheUser{ID: 1, Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("user:1"); got != `{"id":1,"name":"a"}` {
+		t.Fatalf("stored=%q", got)
+	}
+
+	v, err := c.GetOrLoad(context.Background(), 1, func(context.Context, int) (cacheUser, error) {
+		t.Fatal("loader should not be called on redis hit")
+		return cacheUser{}, nil
+	})
+	if err != nil || v != (cacheUser{ID: 1, Name: "a"}) {
+		t.Fatalf("v=%+v err=%v", v, err)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	c, mr := newTestCache(t, WithCacheNegativeTTL(30*time.Second))
+	calls := 0
+	loader := func(context.Context, int) (cacheUser, error) {
+		calls++
+		return cacheUser{}, ErrCacheNotFound
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrLoad(context.Background(), 1, loader); !errors.Is(err, ErrCacheNotFound) {
+			t.Fatalf("call %d err=%v", i, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("negative result should be served from redis, loader calls=%d", calls)
+	}
+	if got, _ := mr.Get("user:1"); got != cacheNegativeValue {
+		t.Fatalf("stored=%q", got)
+	}
+	if ttl := mr.TTL("user:1"); ttl != 30*time.Second {
+		t.Fatalf("negative ttl=%s", ttl)
+	}
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	c, _ := newTestCache(t)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(_ context.Context, id int) (cacheUser, error) {
+		calls.Add(1)
+		<-release
+		return cacheUser{ID: id, Name: "a"}, nil
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), 1, loader)
+			if err == nil && v.Name != "a" {
+				err = errors.Errorf("v=%+v", v)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // 等待并发调用都进入 singleflight
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("loader calls=%d", got)
+	}
+}
+
+func TestCacheCanceledCallerDoesNotCancelLoad(t *testing.T) {
+	c, _ := newTestCache(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var (
+		loadErr atomic.Value
+		once    sync.Once
+	)
+	loader := func(ctx context.Context, id int) (cacheUser, error) {
+		once.Do(func() { close(started) })
+		<-release
+		if err := ctx.Err(); err != nil {
+			loadErr.Store(err)
+			return cacheUser{}, err
+		}
+		return cacheUser{ID: id, Name: "a"}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, 1, loader)
+		canceled <- err
+	}()
+	<-started
+
+	shared := make(chan error, 1)
+	go func() {
+		v, err := c.GetOrLoad(context.Background(), 1, loader)
+		if err == nil && v.Name != "a" {
+			err = errors.Errorf("v=%+v", v)
+		}
+		shared <- err
+	}()
+
+	cancel()
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled caller err=%v", err)
+	}
+	time.Sleep(20 * time.Millisecond) // 确保第二个调用方已合并到进行中的回源
+	close(release)
+	if err := <-shared; err != nil {
+		t.Fatalf("shared caller err=%v", err)
+	}
+	if err := loadErr.Load(); err != nil {
+		t.Fatalf("load ctx canceled: %v", err)
+	}
+}
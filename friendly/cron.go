@@ -0,0 +1,196 @@
+package friendly
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule 计算给定时间之后的下一次触发时间
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule 解析调度表达式：
+// - 标准 5 段 cron：分 时 日 月 周，支持 * , - / 以及月份 / 星期英文缩写（周日为 0 或 7）
+// - 预定义：@yearly @annually @monthly @weekly @daily @midnight @hourly
+// - 固定间隔：@every 30s（time.ParseDuration 格式，最小 1s）
+// 日与周同时受限时按标准 cron 语义取并集。时区由 Next 入参的 Location 决定。
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "parse schedule %q", spec)
+		}
+		if d < time.Second {
+			return nil, errors.Errorf("parse schedule %q: interval must be >= 1s", spec)
+		}
+		return everySchedule(d), nil
+	}
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("parse schedule %q: expect 5 fields, got %d", spec, len(fields))
+	}
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseCronField(fields[0], cronBounds{0, 59, nil}); err != nil {
+		return nil, errors.WithMessagef(err, "parse schedule %q minute", spec)
+	}
+	if s.hour, err = parseCronField(fields[1], cronBounds{0, 23, nil}); err != nil {
+		return nil, errors.WithMessagef(err, "parse schedule %q hour", spec)
+	}
+	if s.dom, err = parseCronField(fields[2], cronBounds{1, 31, nil}); err != nil {
+		return nil, errors.WithMessagef(err, "parse schedule %q day of month", spec)
+	}
+	if s.month, err = parseCronField(fields[3], cronBounds{1, 12, cronMonths}); err != nil {
+		return nil, errors.WithMessagef(err, "parse schedule %q month", spec)
+	}
+	if s.dow, err = parseCronField(fields[4], cronBounds{0, 7, cronWeekdays}); err != nil {
+		return nil, errors.WithMessagef(err, "parse schedule %q day of week", spec)
+	}
+	if s.dow&(1<<7) != 0 { // 7 与 0 均表示周日
+		s.dow |= 1
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// everySchedule 固定间隔调度，Next 为 t + d（截断到秒）
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e)).Truncate(time.Second)
+}
+
+// cronSchedule 每个字段为位图，第 i 位表示取值 i
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// Next 按字段从大到小逐级推进，找不到（如 2 月 30 日）时返回零值
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+type cronBounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMonths = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronWeekdays = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// parseCronField 解析单个字段（逗号分隔的 * / a / a-b，均可带 /step）为位图
+func parseCronField(field string, b cronBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.Errorf("invalid step %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			i := strings.IndexByte(rng, '-')
+			var err error
+			if lo, err = cronValue(rng[:i], b); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(rng[i+1:], b); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := cronValue(rng, b)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+		if lo > hi {
+			return 0, errors.Errorf("invalid range %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, b cronBounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, errors.Errorf("value %d out of range [%d, %d]", v, b.min, b.max)
+	}
+	return v, nil
+}
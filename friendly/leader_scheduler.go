@@ -0,0 +1,292 @@
+package friendly
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// 任务运行状态
+const (
+	JobStatusRunning = "running"
+	JobStatusOK      = "ok"
+	JobStatusError   = "error"
+	JobStatusTimeout = "timeout"
+	JobStatusPanic   = "panic"
+)
+
+// JobFunc 定时任务；ctx 在超时或失去领导权时被取消
+type JobFunc func(ctx context.Context) error
+
+// JobRun Redis 中记录的最近一次运行
+type JobRun struct {
+	Tick       time.Time // 本次对应的调度时间点
+	StartedAt  time.Time
+	FinishedAt time.Time // 运行中为零值
+	Status     string
+	Error      string
+	Epoch      int64 // 运行时 leader 的 fencing token
+}
+
+type jobOptions struct {
+	timeout time.Duration
+}
+
+// JobOption Register 的可选参数
+type JobOption func(o *jobOptions)
+
+// WithJobTimeout 单次运行超时，默认 5m；<=0 时不限制
+func WithJobTimeout(d time.Duration) JobOption {
+	return func(o *jobOptions) { o.timeout = d }
+}
+
+type scheduledJob struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+	opts     jobOptions
+}
+
+// LeaderScheduler 仅在本实例为 leader 时运行的定时任务调度器：
+// - Run 作为 RedisLeader / LeaseLeader 的 onStarted 回调，失去领导权时 ctx 取消，全部任务随之退出
+// - 每个调度时间点先在 Redis 中原子登记（tick 只增不减），新 leader 不会重复执行已登记的时间点
+// - 上任时若错过了调度时间点，补跑一次后按调度继续
+// - 每个任务独立 goroutine 串行运行，有各自的超时与 panic 恢复，结果写回 Redis
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+type LeaderScheduler struct {
+	rdb    redis.Cmdable
+	prefix string
+	loc    *time.Location
+	logger *log.Helper
+
+	mu   sync.Mutex
+	jobs []*scheduledJob
+}
+
+// NewLeaderScheduler 创建调度器，任务状态存放于 prefix:<name>；loc 为 cron 表达式所在时区，nil 时为 time.Local
+func NewLeaderScheduler(rdb redis.Cmdable, prefix string, loc *time.Location, logger log.Logger) *LeaderScheduler {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &LeaderScheduler{
+		rdb:    rdb,
+		prefix: prefix,
+		loc:    loc,
+		logger: log.NewHelper(log.With(logger, "module", "leader/scheduler")),
+	}
+}
+
+// Register 注册任务，spec 语法见 ParseSchedule；需在 Run 之前调用，name 不可重复
+func (s *LeaderScheduler) Register(name, spec string, fn JobFunc, opts ...JobOption) error {
+	sched, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	o := jobOptions{timeout: 5 * time.Minute}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.name == name {
+			return errors.Errorf("job %q already registered", name)
+		}
+	}
+	s.jobs = append(s.jobs, &scheduledJob{name: name, spec: spec, schedule: sched, fn: fn, opts: o})
+	return nil
+}
+
+// JobKey 返回任务状态的 Redis key
+func (s *LeaderScheduler) JobKey(name string) string {
+	return s.prefix + ":" + name
+}
+
+// Run 运行全部任务直到 ctx 取消，并等待在途任务退出后返回
+func (s *LeaderScheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]*scheduledJob(nil), s.jobs...)
+	s.mu.Unlock()
+
+	epoch, _ := EpochFromContext(ctx)
+	s.logger.Infof("scheduler started, jobs=%d epoch=%d", len(jobs), epoch)
+
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j *scheduledJob) {
+			defer wg.Done()
+			s.jobLoop(ctx, j, epoch)
+		}(j)
+	}
+	wg.Wait()
+	s.logger.Infof("scheduler stopped, epoch=%d", epoch)
+}
+
+func (s *LeaderScheduler) jobLoop(ctx context.Context, j *scheduledJob, epoch int64) {
+	var last JobRun
+	for {
+		var err error
+		if last, err = s.LastRun(ctx, j.name); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warnf("load job state failed, job=%s err=%v", j.name, err)
+		sleepJitter(ctx, time.Second)
+	}
+
+	now := time.Now().In(s.loc)
+	var next time.Time
+	if last.Tick.IsZero() {
+		next = j.schedule.Next(now)
+	} else if next = j.schedule.Next(last.Tick.In(s.loc)); next.Before(now) {
+		next = now.Truncate(time.Second) // 错过的时间点只补跑一次
+	}
+
+	for {
+		if next.IsZero() {
+			s.logger.Errorf("job has no next run time, job=%s spec=%q", j.name, j.spec)
+			return
+		}
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		claimed, err := s.claim(ctx, j.name, next, epoch)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warnf("claim job failed, job=%s tick=%s err=%v", j.name, next.Format(time.RFC3339), err)
+		case !claimed:
+			s.logger.Infof("job tick already run, job=%s tick=%s", j.name, next.Format(time.RFC3339))
+		default:
+			s.execute(ctx, j, next, epoch)
+		}
+
+		if next = j.schedule.Next(next); !next.IsZero() && next.Before(time.Now()) {
+			next = j.schedule.Next(time.Now().In(s.loc)) // 运行超过一个周期时跳过积压的时间点
+		}
+	}
+}
+
+// claimScript 仅当 tick 大于已登记的 tick 时登记本次运行
+const claimScript = `
+local last = tonumber(redis.call('HGET', KEYS[1], 'tick') or '0')
+if last >= tonumber(ARGV[1]) then
+  return 0
+end
+redis.call('HSET', KEYS[1], 'tick', ARGV[1], 'started_at', ARGV[2], 'epoch', ARGV[3],
+  'status', 'running', 'finished_at', '', 'error', '')
+return 1
+`
+
+func (s *LeaderScheduler) claim(ctx context.Context, name string, tick time.Time, epoch int64) (bool, error) {
+	n, err := s.rdb.Eval(ctx, claimScript, []string{s.JobKey(name)},
+		tick.UnixMilli(), time.Now().UnixMilli(), epoch,
+	).Int64()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return n == 1, nil
+}
+
+// finishScript 仅当登记的 tick / epoch 仍是本次运行时写回结果，
+// 避免超时运行或已下台 leader 的结果覆盖后续时间点的登记
+const finishScript = `
+if redis.call('HGET', KEYS[1], 'tick') ~= ARGV[1] or redis.call('HGET', KEYS[1], 'epoch') ~= ARGV[2] then
+  return 0
+end
+redis.call('HSET', KEYS[1], 'status', ARGV[3], 'error', ARGV[4], 'finished_at', ARGV[5])
+return 1
+`
+
+func (s *LeaderScheduler) execute(ctx context.Context, j *scheduledJob, tick time.Time, epoch int64) {
+	runCtx, cancel := ctx, context.CancelFunc(func() {})
+	if j.opts.timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, j.opts.timeout)
+	}
+	start := time.Now()
+	status, err := runJob(runCtx, j.fn)
+	cancel()
+
+	elapsed := time.Since(start)
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+		s.logger.Errorf("job failed, job=%s tick=%s status=%s latency=%s err=%+v",
+			j.name, tick.Format(time.RFC3339), status, elapsed, err)
+	} else {
+		s.logger.Infof("job done, job=%s tick=%s latency=%s", j.name, tick.Format(time.RFC3339), elapsed)
+	}
+
+	// 即使已失去领导权也尝试写回结果，登记已被后续时间点覆盖时放弃
+	n, err := s.rdb.Eval(context.WithoutCancel(ctx), finishScript, []string{s.JobKey(j.name)},
+		tick.UnixMilli(), epoch, status, errMsg, time.Now().UnixMilli(),
+	).Int64()
+	switch {
+	case err != nil:
+		s.logger.Warnf("record job result failed, job=%s err=%v", j.name, err)
+	case n == 0:
+		s.logger.Warnf("job run superseded, result not recorded, job=%s tick=%s", j.name, tick.Format(time.RFC3339))
+	}
+}
+
+// runJob 运行任务并归类结果，panic 视为失败
+func runJob(ctx context.Context, fn JobFunc) (status string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			status, err = JobStatusPanic, errors.WithStack(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	if err = fn(ctx); err == nil {
+		return JobStatusOK, nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return JobStatusTimeout, err
+	}
+	return JobStatusError, err
+}
+
+// LastRun 读取任务最近一次运行记录；从未运行时返回零值
+func (s *LeaderScheduler) LastRun(ctx context.Context, name string) (JobRun, error) {
+	m, err := s.rdb.HGetAll(ctx, s.JobKey(name)).Result()
+	if err != nil {
+		return JobRun{}, errors.WithStack(err)
+	}
+	return JobRun{
+		Tick:       unixMilliField(m["tick"]),
+		StartedAt:  unixMilliField(m["started_at"]),
+		FinishedAt: unixMilliField(m["finished_at"]),
+		Status:     m["status"],
+		Error:      m["error"],
+		Epoch:      int64Field(m["epoch"]),
+	}, nil
+}
+
+func unixMilliField(s string) time.Time {
+	if ms := int64Field(s); ms > 0 {
+		return time.UnixMilli(ms)
+	}
+	return time.Time{}
+}
+
+func int64Field(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
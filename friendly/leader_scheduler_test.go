@@ -0,0 +1,126 @@
+package friendly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLeaderSchedulerRegister(t *testing.T) {
+	s := NewLeaderScheduler(nil, "jobs", time.UTC, log.DefaultLogger)
+	noop := func(context.Context) error { return nil }
+
+	if err := s.Register("a", "@every 1m", noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register("a", "@every 1m", noop); err == nil {
+		t.Fatal("duplicate name should fail")
+	}
+	if err := s.Register("b", "bad spec", noop); err == nil {
+		t.Fatal("invalid spec should fail")
+	}
+	if got := s.JobKey("a"); got != "jobs:a" {
+		t.Fatalf("key=%q", got)
+	}
+}
+
+func TestRunJobStatus(t *testing.T) {
+	status, err := runJob(context.Background(), func(context.Context) error { return nil })
+	if status != JobStatusOK || err != nil {
+		t.Fatalf("status=%s err=%v", status, err)
+	}
+
+	status, err = runJob(context.Background(), func(context.Context) error { return errors.New("x") })
+	if status != JobStatusError || err == nil {
+		t.Fatalf("status=%s err=%v", status, err)
+	}
+
+	status, err = runJob(context.Background(), func(context.Context) error { panic("boom") })
+	if status != JobStatusPanic || err == nil {
+		t.Fatalf("status=%s err=%v", status, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	status, err = runJob(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if status != JobStatusTimeout || err == nil {
+		t.Fatalf("status=%s err=%v", status, err)
+	}
+}
+
+func TestLeaderSchedulerRunStopsWithContext(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	s := NewLeaderScheduler(rdb, "jobs", nil, log.DefaultLogger)
+	if err := s.Register("a", "@every 1s", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run should return after ctx is canceled")
+	}
+}
+
+func TestLeaderSchedulerExecuteRecordsResult(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	s := NewLeaderScheduler(rdb, "jobs", time.UTC, log.DefaultLogger)
+	ctx := context.Background()
+	tick := time.Now().Truncate(time.Second)
+
+	if ok, err := s.claim(ctx, "a", tick, 1); err != nil || !ok {
+		t.Fatalf("claim ok=%v err=%v", ok, err)
+	}
+	s.execute(ctx, &scheduledJob{name: "a", fn: func(context.Context) error { return errors.New("x") }}, tick, 1)
+
+	run, err := s.LastRun(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != JobStatusError || run.Error != "x" || run.FinishedAt.IsZero() || !run.Tick.Equal(tick) {
+		t.Fatalf("run=%+v", run)
+	}
+}
+
+func TestLeaderSchedulerExecuteSuperseded(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	s := NewLeaderScheduler(rdb, "jobs", time.UTC, log.DefaultLogger)
+	ctx := context.Background()
+	tick := time.Now().Truncate(time.Second)
+	next := tick.Add(time.Minute)
+
+	if ok, err := s.claim(ctx, "a", tick, 1); err != nil || !ok {
+		t.Fatalf("claim ok=%v err=%v", ok, err)
+	}
+	// 运行超过一个周期，期间新 leader 已登记下一个时间点
+	s.execute(ctx, &scheduledJob{name: "a", fn: func(ctx context.Context) error {
+		if ok, err := s.claim(ctx, "a", next, 2); err != nil || !ok {
+			t.Errorf("claim next ok=%v err=%v", ok, err)
+		}
+		return nil
+	}}, tick, 1)
+
+	run, err := s.LastRun(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != JobStatusRunning || !run.FinishedAt.IsZero() || !run.Tick.Equal(next) || run.Epoch != 2 {
+		t.Fatalf("superseded run overwrote next tick: %+v", run)
+	}
+}
@@ -0,0 +1,114 @@
+package friendly
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LeaseStore 领导者选举的租约存储后端。
+// 实现需保证：
+// - TryAcquire 仅在租约空闲（不存在/已过期/已释放）时成功，并返回该 key 上单调递增的 epoch（>0）；未抢到返回 0
+// - 存在未过期的继任者提示且不是 id 时，TryAcquire 不应成功
+// - Renew / Release 仅当租约当前持有者为 id 时生效
+type LeaseStore interface {
+	TryAcquire(ctx context.Context, key, id string, ttl time.Duration) (int64, error)
+	Renew(ctx context.Context, key, id string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key, id string, opt ReleaseOptions) error
+	Holder(ctx context.Context, key string) (string, error)
+}
+
+// ReleaseOptions 释放租约时的附加参数
+type ReleaseOptions struct {
+	Successor string        // 继任者提示，空串表示不指定
+	HintTTL   time.Duration // 继任者提示的有效期
+}
+
+var _ LeaseStore = (*MemoryLeaseStore)(nil)
+
+type memoryLease struct {
+	holder         string
+	expiresAt      time.Time
+	epoch          int64
+	successor      string
+	successorUntil time.Time
+}
+
+// MemoryLeaseStore 进程内租约存储，用于单元测试或单实例部署
+type MemoryLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]*memoryLease
+	now    func() time.Time
+}
+
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{
+		leases: make(map[string]*memoryLease),
+		now:    time.Now,
+	}
+}
+
+func (s *MemoryLeaseStore) TryAcquire(_ context.Context, key, id string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	ls, ok := s.leases[key]
+	if !ok {
+		ls = &memoryLease{}
+		s.leases[key] = ls
+	}
+	if ls.holder != "" && now.Before(ls.expiresAt) {
+		return 0, nil
+	}
+	if ls.successor != "" && ls.successor != id && now.Before(ls.successorUntil) {
+		return 0, nil
+	}
+
+	ls.holder = id
+	ls.expiresAt = now.Add(ttl)
+	ls.successor = ""
+	ls.epoch++
+	return ls.epoch, nil
+}
+
+func (s *MemoryLeaseStore) Renew(_ context.Context, key, id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	ls, ok := s.leases[key]
+	if !ok || ls.holder != id || !now.Before(ls.expiresAt) {
+		return false, nil
+	}
+	ls.expiresAt = now.Add(ttl)
+	return true, nil
+}
+
+func (s *MemoryLeaseStore) Release(_ context.Context, key, id string, opt ReleaseOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ls, ok := s.leases[key]
+	if !ok || ls.holder != id {
+		return nil
+	}
+	ls.holder = ""
+	ls.expiresAt = time.Time{}
+	if opt.Successor != "" {
+		ls.successor = opt.Successor
+		ls.successorUntil = s.now().Add(opt.HintTTL)
+	}
+	return nil
+}
+
+func (s *MemoryLeaseStore) Holder(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ls, ok := s.leases[key]
+	if !ok || !s.now().Before(ls.expiresAt) {
+		return "", nil
+	}
+	return ls.holder, nil
+}
@@ -0,0 +1,308 @@
+package friendly
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+type epochCtxKey struct{}
+
+// LeaseLeader 基于 LeaseStore 的领导者选举：
+// - TryAcquire 获取租约（TTL=ttl），每次成功得到单调递增的 epoch（fencing token）
+// - 周期性续期（仅当持有者为本实例 id 时）
+// - 丢失领导权后自动退出工作回调并重试抢占
+// - Stop 时安全释放（仅释放本实例持有的租约）
+// - Resign 时等待工作退出后释放，可指定继任者并进入冷却期
+type LeaseLeader struct {
+	store      LeaseStore
+	key        string
+	id         string
+	ttl        time.Duration
+	renewEvery time.Duration
+	logger     *log.Helper
+	onStarted  func(ctx context.Context)
+	onStopped  func(context.Context)
+
+	epoch   atomic.Int64 // 当前任期的 fencing token，非 leader 时为 0
+	events  leaderEventHub
+	running atomic.Bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	exited  atomic.Pointer[chan struct{}] // 本轮选举循环退出时关闭
+
+	resignCh chan resignRequest
+}
+
+// NewLeaseLeader 使用任意 LeaseStore 后端构造领导者选举
+func NewLeaseLeader(
+	store LeaseStore,
+	key string,
+	ttl, renewEvery time.Duration,
+	logger log.Logger,
+	onStarted func(context.Context),
+	onStopped func(context.Context),
+) *LeaseLeader {
+	return newLeaseLeader(store, "leader/lease", key, ttl, renewEvery, logger, onStarted, onStopped)
+}
+
+func newLeaseLeader(
+	store LeaseStore,
+	module string,
+	key string,
+	ttl, renewEvery time.Duration,
+	logger log.Logger,
+	onStarted func(context.Context),
+	onStopped func(context.Context),
+) *LeaseLeader {
+	if renewEvery <= 0 || (ttl > 0 && renewEvery >= ttl) {
+		renewEvery = ttl / 3
+	}
+	return &LeaseLeader{
+		store:      store,
+		key:        key,
+		id:         randID(),
+		ttl:        ttl,
+		renewEvery: renewEvery,
+		logger:     log.NewHelper(log.With(logger, "module", module)),
+		onStarted:  onStarted,
+		onStopped:  onStopped,
+		resignCh:   make(chan resignRequest),
+	}
+}
+
+// Start 启动领导者循环：抢占 → 续期 → 丢失后重试
+func (l *LeaseLeader) Start(ctx context.Context) error {
+	if !l.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	l.cancel = cancel
+	exited := make(chan struct{})
+	l.exited.Store(&exited)
+
+	l.wg.Add(1)
+	go l.loop(runCtx, exited)
+
+	l.logger.Infof("leader loop started, key=%s ttl=%s renew=%s id=%s",
+		l.key, l.ttl, l.renewEvery, l.id)
+	return nil
+}
+
+// Stop 停止并释放锁
+func (l *LeaseLeader) Stop(ctx context.Context) error {
+	if !l.running.CompareAndSwap(true, false) {
+		return nil
+	}
+	if l.cancel != nil {
+		l.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// leaderTerm 一个任期内的工作状态
+type leaderTerm struct {
+	epoch  int64
+	cancel context.CancelFunc
+	done   chan struct{} // onStarted 返回后关闭
+}
+
+func (l *LeaseLeader) loop(ctx context.Context, exited chan struct{}) {
+	defer l.wg.Done()
+	defer close(exited)
+
+	var (
+		term          *leaderTerm
+		cooldownUntil time.Time
+	)
+
+	renewTicker := time.NewTicker(l.renewEvery)
+	defer renewTicker.Stop()
+
+	backoff := 300 * time.Millisecond
+
+	for {
+		if ctx.Err() != nil {
+			l.stopIfLeader(&term, true, "leader loop stopped") // ctx 退出：需要闭环 onStopped
+			return
+		}
+
+		if term == nil {
+			select {
+			case req := <-l.resignCh:
+				// 非 leader 时收到 Resign：仅进入冷却期
+				cooldownUntil = time.Now().Add(req.cooldown)
+				req.done <- nil
+				continue
+			default:
+			}
+			if wait := time.Until(cooldownUntil); wait > 0 {
+				sleepJitter(ctx, min(wait, backoff))
+				continue
+			}
+
+			epoch, err := l.store.TryAcquire(ctx, l.key, l.id, l.ttl)
+			if err != nil {
+				l.logger.Warnf("acquire error: %+v", err)
+				sleepJitter(ctx, backoff)
+				continue
+			}
+			if epoch == 0 {
+				sleepJitter(ctx, backoff)
+				continue
+			}
+
+			term = l.startWork(ctx, epoch)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			l.stopIfLeader(&term, true, "leader loop stopped")
+			return
+
+		case req := <-l.resignCh:
+			req.done <- l.resignTerm(&term, req)
+			cooldownUntil = time.Now().Add(req.cooldown)
+
+		case <-renewTicker.C:
+			ok, err := l.store.Renew(ctx, l.key, l.id, l.ttl)
+			if err != nil {
+				// 出错不等于丢失领导权，下一轮再尝试
+				l.logger.Warnf("renew error: %+v", err)
+				l.emit(LeaderEventRenewFailed, term.epoch, err.Error())
+				continue
+			}
+			if !ok {
+				// 领导权被抢/丢失：停止工作并闭环 onStopped，但不 release（因为已不是我们的锁）
+				l.stopIfLeader(&term, false, "lock no longer owned")
+				continue
+			}
+			l.emit(LeaderEventRenewed, term.epoch, "")
+		}
+	}
+}
+
+func (l *LeaseLeader) startWork(parent context.Context, epoch int64) *leaderTerm {
+	l.epoch.Store(epoch)
+	l.logger.Infof("leadership acquired, key=%s epoch=%d id=%s", l.key, epoch, l.id)
+
+	workCtx, cancel := context.WithCancel(context.WithValue(parent, epochCtxKey{}, epoch))
+	term := &leaderTerm{epoch: epoch, cancel: cancel, done: make(chan struct{})}
+	l.emit(LeaderEventAcquired, epoch, "")
+	go func() {
+		defer close(term.done)
+		if l.onStarted != nil {
+			l.onStarted(workCtx)
+		}
+	}()
+	return term
+}
+
+// stopIfLeader：保证 onStarted/onStopped 闭环；releaseOnlyWhenOwned=true 时会尝试释放锁
+func (l *LeaseLeader) stopIfLeader(term **leaderTerm, releaseOnlyWhenOwned bool, reason string) {
+	if term == nil || *term == nil {
+		return
+	}
+	t := *term
+	*term = nil
+	l.epoch.Store(0)
+
+	// 先 cancel，让工作尽快停，再释放锁，降低“新 leader 已开始但旧 worker 仍在跑”的窗口
+	t.cancel()
+
+	if l.onStopped != nil {
+		go l.onStopped(context.Background())
+	}
+
+	if releaseOnlyWhenOwned {
+		l.release(context.Background(), ReleaseOptions{})
+		l.emit(LeaderEventReleased, t.epoch, reason)
+	} else {
+		l.emit(LeaderEventLost, t.epoch, reason)
+	}
+}
+
+// resignTerm 主动让位：cancel 工作 ctx → 等待 onStarted 返回（受 req.ctx 限制）→ 释放租约
+// 等待超时仍会释放锁（旧 worker 已被 cancel，写入可借助 fencing token 拒绝），并返回超时错误
+func (l *LeaseLeader) resignTerm(term **leaderTerm, req resignRequest) error {
+	t := *term
+	*term = nil
+	l.epoch.Store(0)
+	t.cancel()
+
+	var waitErr error
+	select {
+	case <-t.done:
+	case <-req.ctx.Done():
+		waitErr = errors.WithMessage(req.ctx.Err(), "wait onStarted return")
+		l.logger.Warnf("resign: onStarted did not return in time, key=%s epoch=%d", l.key, t.epoch)
+	}
+
+	if l.onStopped != nil {
+		go l.onStopped(context.Background())
+	}
+
+	relCtx, cancel := context.WithTimeout(context.Background(), l.ttl)
+	defer cancel()
+	l.release(relCtx, ReleaseOptions{Successor: req.successor, HintTTL: l.ttl})
+	l.emit(LeaderEventReleased, t.epoch, "resigned")
+	l.logger.Infof("leadership resigned, key=%s epoch=%d successor=%q cooldown=%s",
+		l.key, t.epoch, req.successor, req.cooldown)
+	return waitErr
+}
+
+func (l *LeaseLeader) release(ctx context.Context, opt ReleaseOptions) {
+	if err := l.store.Release(ctx, l.key, l.id, opt); err != nil {
+		l.logger.Warnf("release error: %+v", err)
+	}
+}
+
+// CurrentEpoch 返回本实例当前任期的 fencing token；非 leader 时返回 0
+func (l *LeaseLeader) CurrentEpoch() int64 {
+	return l.epoch.Load()
+}
+
+// EpochFromContext 从 onStarted 收到的工作 ctx 中取出本任期的 fencing token
+func EpochFromContext(ctx context.Context) (int64, bool) {
+	epoch, ok := ctx.Value(epochCtxKey{}).(int64)
+	return epoch, ok
+}
+
+func randID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func sleepJitter(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d + time.Duration(randByte()%250)*time.Millisecond)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+func randByte() byte {
+	var b [1]byte
+	_, _ = rand.Read(b[:])
+	return b[0]
+}
@@ -0,0 +1,110 @@
+package friendly
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LeaderEventType 领导权状态变化类型
+type LeaderEventType string
+
+const (
+	LeaderEventAcquired    LeaderEventType = "acquired"     // 抢占成功，开始工作
+	LeaderEventRenewed     LeaderEventType = "renewed"      // 续期成功
+	LeaderEventRenewFailed LeaderEventType = "renew_failed" // 续期出错（网络等），尚未确认丢失
+	LeaderEventLost        LeaderEventType = "lost"         // 锁已不属于本实例（过期/被抢）
+	LeaderEventReleased    LeaderEventType = "released"     // 本实例主动退出并释放锁
+)
+
+// LeaderEvent 领导权变化事件
+type LeaderEvent struct {
+	Type   LeaderEventType
+	Key    string    // 锁 key
+	ID     string    // 本实例 id
+	Epoch  int64     // 事件对应任期的 fencing token
+	At     time.Time // 事件发生时间
+	Reason string    // 附加原因，如错误信息
+}
+
+// leaderEventHub 管理事件订阅者；发送不阻塞，订阅方消费过慢时丢弃事件
+type leaderEventHub struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]chan LeaderEvent
+}
+
+func (h *leaderEventHub) subscribe(buf int) (<-chan LeaderEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs == nil {
+		h.subs = make(map[int]chan LeaderEvent)
+	}
+	id := h.next
+	h.next++
+	ch := make(chan LeaderEvent, buf)
+	h.subs[id] = ch
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subs, id)
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+func (h *leaderEventHub) publish(ev LeaderEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe 订阅领导权变化事件，buf 为通道缓冲大小（<=0 时取 16）。
+// 事件以非阻塞方式投递，消费过慢会丢失事件；不再需要时调用返回的 cancel 关闭通道。
+func (l *LeaseLeader) Subscribe(buf int) (<-chan LeaderEvent, func()) {
+	if buf <= 0 {
+		buf = 16
+	}
+	return l.events.subscribe(buf)
+}
+
+// IsLeader 本实例当前是否持有领导权
+func (l *LeaseLeader) IsLeader() bool {
+	return l.epoch.Load() > 0
+}
+
+// ID 返回本实例在锁中写入的 id
+func (l *LeaseLeader) ID() string {
+	return l.id
+}
+
+// Key 返回锁 key
+func (l *LeaseLeader) Key() string {
+	return l.key
+}
+
+// Leader 从租约存储读取当前持有者的实例 id；无人持有时返回空串
+func (l *LeaseLeader) Leader(ctx context.Context) (string, error) {
+	return l.store.Holder(ctx, l.key)
+}
+
+func (l *LeaseLeader) emit(typ LeaderEventType, epoch int64, reason string) {
+	l.events.publish(LeaderEvent{
+		Type:   typ,
+		Key:    l.key,
+		ID:     l.id,
+		Epoch:  epoch,
+		At:     time.Now(),
+		Reason: reason,
+	})
+}
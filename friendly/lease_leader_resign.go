@@ -0,0 +1,69 @@
+package friendly
+
+import (
+	"context"
+	"time"
+)
+
+type resignRequest struct {
+	ctx       context.Context
+	successor string
+	cooldown  time.Duration
+	done      chan error
+}
+
+// ResignOption Resign 的可选参数
+type ResignOption func(r *resignRequest)
+
+// WithSuccessor 指定优先接任的实例 id（见 LeaseLeader.ID）。
+// 提示在一个 ttl 内有效，期间其他实例不会抢占；继任者不在线时提示过期后恢复自由竞争。
+func WithSuccessor(id string) ResignOption {
+	return func(r *resignRequest) { r.successor = id }
+}
+
+// WithCooldown 让位后本实例在 d 内不参与抢占，默认等于 ttl
+func WithCooldown(d time.Duration) ResignOption {
+	return func(r *resignRequest) { r.cooldown = d }
+}
+
+// Resign 主动让出领导权，依次：cancel 工作 ctx → 等待 onStarted 返回（最长到 ctx 截止）→ 释放租约（仅当仍由本实例持有）。
+// 与 Stop 不同，选举循环继续运行，冷却期结束后本实例会重新参与抢占。
+// 未启动或选举循环已退出时直接返回 nil；等待 onStarted 超时时锁仍会释放，并返回超时错误。
+func (l *LeaseLeader) Resign(ctx context.Context, opts ...ResignOption) error {
+	if !l.running.Load() {
+		return nil
+	}
+	exited := l.exited.Load()
+	if exited == nil {
+		return nil
+	}
+	req := resignRequest{
+		ctx:      ctx,
+		cooldown: l.ttl,
+		done:     make(chan error, 1),
+	}
+	for _, o := range opts {
+		o(&req)
+	}
+
+	// 检查 running 之后循环可能已被 Stop 或 ctx 取消结束，退出时已释放租约，视同未启动
+	select {
+	case l.resignCh <- req:
+	case <-*exited:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// 循环侧会在 ctx 截止后停止等待并完成释放，这里必须等到结果以保证“释放已发生”
+	select {
+	case err := <-req.done:
+		return err
+	case <-*exited:
+		select {
+		case err := <-req.done:
+			return err
+		default:
+			return nil
+		}
+	}
+}
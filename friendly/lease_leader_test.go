@@ -0,0 +1,202 @@
+package friendly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func newTestLeader(store LeaseStore, onStarted func(context.Context)) *LeaseLeader {
+	return NewLeaseLeader(store, "job", time.Second, 0, log.DefaultLogger, onStarted, nil)
+}
+
+func TestEpochFromContext(t *testing.T) {
+	t.Run("missing epoch", func(t *testing.T) {
+		if _, ok := EpochFromContext(context.Background()); ok {
+			t.Fatal("expected no epoch in empty context")
+		}
+	})
+
+	t.Run("epoch from work context", func(t *testing.T) {
+		got := make(chan int64, 1)
+		l := newTestLeader(NewMemoryLeaseStore(), func(ctx context.Context) {
+			epoch, _ := EpochFromContext(ctx)
+			got <- epoch
+		})
+
+		term := l.startWork(context.Background(), 7)
+		if epoch := <-got; epoch != 7 {
+			t.Fatalf("unexpected epoch in work context: %d", epoch)
+		}
+		if l.CurrentEpoch() != 7 {
+			t.Fatalf("unexpected current epoch: %d", l.CurrentEpoch())
+		}
+
+		l.stopIfLeader(&term, false, "test")
+		if l.CurrentEpoch() != 0 {
+			t.Fatalf("expected epoch reset after stop, got %d", l.CurrentEpoch())
+		}
+	})
+}
+
+func TestLeaseLeaderEvents(t *testing.T) {
+	l := newTestLeader(NewMemoryLeaseStore(), nil)
+	events, cancelSub := l.Subscribe(4)
+
+	if l.IsLeader() {
+		t.Fatal("expected not leader before start")
+	}
+
+	term := l.startWork(context.Background(), 3)
+	if !l.IsLeader() {
+		t.Fatal("expected leader after startWork")
+	}
+	l.stopIfLeader(&term, false, "lock no longer owned")
+	if l.IsLeader() {
+		t.Fatal("expected not leader after stop")
+	}
+
+	ev := <-events
+	if ev.Type != LeaderEventAcquired || ev.Epoch != 3 || ev.Key != "job" || ev.ID != l.ID() {
+		t.Fatalf("unexpected acquired event: %+v", ev)
+	}
+	ev = <-events
+	if ev.Type != LeaderEventLost || ev.Epoch != 3 || ev.Reason != "lock no longer owned" {
+		t.Fatalf("unexpected lost event: %+v", ev)
+	}
+
+	cancelSub()
+	cancelSub()
+	if _, ok := <-events; ok {
+		t.Fatal("expected closed channel after cancel")
+	}
+}
+
+func TestLeaseLeaderResignTerm(t *testing.T) {
+	t.Run("wait onStarted return", func(t *testing.T) {
+		returned := make(chan struct{})
+		l := newTestLeader(NewMemoryLeaseStore(), func(ctx context.Context) {
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			close(returned)
+		})
+
+		term := l.startWork(context.Background(), 1)
+		err := l.resignTerm(&term, resignRequest{ctx: context.Background()})
+		if err != nil {
+			t.Fatalf("unexpected resign error: %v", err)
+		}
+		select {
+		case <-returned:
+		default:
+			t.Fatal("expected onStarted returned before release")
+		}
+		if term != nil || l.IsLeader() {
+			t.Fatal("expected term cleared after resign")
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		l := newTestLeader(NewMemoryLeaseStore(), func(ctx context.Context) {
+			<-block
+		})
+
+		term := l.startWork(context.Background(), 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := l.resignTerm(&term, resignRequest{ctx: ctx}); err == nil {
+			t.Fatal("expected timeout error")
+		}
+	})
+}
+
+func TestLeaseLeaderResignNotRunning(t *testing.T) {
+	l := newTestLeader(NewMemoryLeaseStore(), nil)
+	if err := l.Resign(context.Background(), WithSuccessor("other"), WithCooldown(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLeaseLeaderResignAfterLoopExit(t *testing.T) {
+	l := newTestLeader(NewMemoryLeaseStore(), nil)
+	ctx := context.Background()
+	_ = l.Start(ctx)
+	_ = l.Stop(ctx)
+	// 模拟 Resign 通过 running 检查后循环才退出
+	l.running.Store(true)
+
+	done := make(chan error, 1)
+	go func() { done <- l.Resign(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("resign blocked after loop exit")
+	}
+}
+
+func TestLeaseLeaderResignConcurrentStop(t *testing.T) {
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		l := newTestLeader(NewMemoryLeaseStore(), func(ctx context.Context) { <-ctx.Done() })
+		_ = l.Start(ctx)
+
+		done := make(chan error, 2)
+		go func() { done <- l.Resign(ctx) }()
+		go func() { done <- l.Stop(ctx) }()
+		for j := 0; j < 2; j++ {
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("resign / stop blocked")
+			}
+		}
+	}
+}
+
+func TestLeaseLeaderLoopWithMemoryStore(t *testing.T) {
+	store := NewMemoryLeaseStore()
+	started := make(chan context.Context, 2)
+	a := newTestLeader(store, func(ctx context.Context) { started <- ctx })
+	b := newTestLeader(store, func(ctx context.Context) { started <- ctx })
+
+	ctx := context.Background()
+	_ = a.Start(ctx)
+	workCtx := <-started
+	if !a.IsLeader() {
+		t.Fatal("expected a to be leader")
+	}
+	if holder, _ := a.Leader(ctx); holder != a.ID() {
+		t.Fatalf("unexpected holder: %s", holder)
+	}
+
+	_ = b.Start(ctx)
+	defer func() { _ = b.Stop(ctx) }()
+
+	if err := a.Resign(ctx, WithSuccessor(b.ID())); err != nil {
+		t.Fatalf("resign failed: %v", err)
+	}
+	if workCtx.Err() == nil {
+		t.Fatal("expected work ctx canceled after resign")
+	}
+
+	<-started
+	if !b.IsLeader() || a.IsLeader() {
+		t.Fatal("expected leadership handed over to b")
+	}
+	if b.CurrentEpoch() != 2 {
+		t.Fatalf("unexpected epoch after handover: %d", b.CurrentEpoch())
+	}
+	if err := a.Stop(ctx); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+}
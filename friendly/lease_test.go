@@ -0,0 +1,58 @@
+package friendly
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLeaseStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryLeaseStore()
+	s.now = func() time.Time { return now }
+
+	epoch, _ := s.TryAcquire(ctx, "k", "a", time.Second)
+	if epoch != 1 {
+		t.Fatalf("expected first epoch 1, got %d", epoch)
+	}
+	if epoch, _ = s.TryAcquire(ctx, "k", "b", time.Second); epoch != 0 {
+		t.Fatal("expected acquire to fail while lease is held")
+	}
+	if ok, _ := s.Renew(ctx, "k", "b", time.Second); ok {
+		t.Fatal("expected renew by non-holder to fail")
+	}
+	if ok, _ := s.Renew(ctx, "k", "a", time.Second); !ok {
+		t.Fatal("expected renew by holder to succeed")
+	}
+	if holder, _ := s.Holder(ctx, "k"); holder != "a" {
+		t.Fatalf("unexpected holder: %s", holder)
+	}
+
+	t.Run("expired lease can be taken over", func(t *testing.T) {
+		now = now.Add(2 * time.Second)
+		if holder, _ := s.Holder(ctx, "k"); holder != "" {
+			t.Fatalf("expected no holder after expiry, got %s", holder)
+		}
+		if epoch, _ = s.TryAcquire(ctx, "k", "b", time.Second); epoch != 2 {
+			t.Fatalf("expected epoch 2, got %d", epoch)
+		}
+	})
+
+	t.Run("successor hint blocks others", func(t *testing.T) {
+		_ = s.Release(ctx, "k", "b", ReleaseOptions{Successor: "c", HintTTL: time.Second})
+		if epoch, _ = s.TryAcquire(ctx, "k", "a", time.Second); epoch != 0 {
+			t.Fatal("expected non-successor acquire to fail")
+		}
+		if epoch, _ = s.TryAcquire(ctx, "k", "c", time.Second); epoch != 3 {
+			t.Fatalf("expected successor to acquire with epoch 3, got %d", epoch)
+		}
+	})
+
+	t.Run("release by non-holder is ignored", func(t *testing.T) {
+		_ = s.Release(ctx, "k", "a", ReleaseOptions{})
+		if holder, _ := s.Holder(ctx, "k"); holder != "c" {
+			t.Fatalf("unexpected holder: %s", holder)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package friendly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsOldest(t *testing.T) {
+	c := newLRUCache[string, int](2)
+	c.set("a", 1, time.Minute)
+	c.set("b", 2, time.Minute)
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	c.set("c", 3, time.Minute)
+
+	if _, ok := c.get("b"); ok {
+		t.Fatal("b should be evicted as least recently used")
+	}
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Fatalf("a=%d ok=%v", v, ok)
+	}
+	if v, ok := c.get("c"); !ok || v != 3 {
+		t.Fatalf("c=%d ok=%v", v, ok)
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newLRUCache[string, int](4)
+	c.now = func() time.Time { return now }
+
+	c.set("a", 1, time.Second)
+	now = now.Add(999 * time.Millisecond)
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("a should not expire yet")
+	}
+	now = now.Add(time.Millisecond)
+	if _, ok := c.get("a"); ok {
+		t.Fatal("a should expire")
+	}
+	if c.ll.Len() != 0 || len(c.items) != 0 {
+		t.Fatal("expired entry should be removed")
+	}
+}
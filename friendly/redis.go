@@ -2,14 +2,145 @@ package friendly
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+const (
+	RedisConnectFailFast = "fail_fast" // 启动时 Ping 失败直接返回错误
+	RedisConnectLazy     = "lazy"      // 后台 Ping 只打日志，首次使用时再建连
+)
+
+// RedisConf Redis 客户端调优参数，getter 形式便于直接使用 protobuf 生成的配置结构。
+// 所有字段均可留空（零值 / nil），留空时使用构造器的默认值；非空的 Username / Db 会覆盖 DSN 中的设置。
+type RedisConf interface {
+	GetPoolSize() int32                       // 最大连接数，默认 50
+	GetMinIdleConns() int32                   // 最小空闲连接数，默认 5
+	GetConnMaxLifetime() *durationpb.Duration // 连接最大存活时间，默认 30m
+	GetConnMaxIdleTime() *durationpb.Duration // 空闲连接超时释放，默认 5m
+	GetDialTimeout() *durationpb.Duration     // 建立连接超时，默认 10s
+	GetReadTimeout() *durationpb.Duration     // 读超时，默认 10s
+	GetWriteTimeout() *durationpb.Duration    // 写超时，默认 10s
+	GetPoolTimeout() *durationpb.Duration     // 获取连接最大等待时间，默认 单机 20s / 集群 10s
+
+	GetUsername() string   // ACL 用户名
+	GetDb() int32          // DB 序号（仅单机）
+	GetClientName() string // CLIENT SETNAME
+
+	GetTlsCaFile() string           // PEM CA 证书，校验服务端
+	GetTlsCertFile() string         // PEM 客户端证书（mTLS）
+	GetTlsKeyFile() string          // PEM 客户端私钥（mTLS）
+	GetTlsServerName() string       // 覆盖校验用的 ServerName
+	GetTlsInsecureSkipVerify() bool // 跳过服务端证书校验（不建议生产使用）
+	GetConnectMode() string         // RedisConnectFailFast / RedisConnectLazy，留空时 单机 fail_fast / 集群 lazy
+}
+
+type emptyRedisConf struct{}
+
+func (emptyRedisConf) GetPoolSize() int32                       { return 0 }
+func (emptyRedisConf) GetMinIdleConns() int32                   { return 0 }
+func (emptyRedisConf) GetConnMaxLifetime() *durationpb.Duration { return nil }
+func (emptyRedisConf) GetConnMaxIdleTime() *durationpb.Duration { return nil }
+func (emptyRedisConf) GetDialTimeout() *durationpb.Duration     { return nil }
+func (emptyRedisConf) GetReadTimeout() *durationpb.Duration     { return nil }
+func (emptyRedisConf) GetWriteTimeout() *durationpb.Duration    { return nil }
+func (emptyRedisConf) GetPoolTimeout() *durationpb.Duration     { return nil }
+func (emptyRedisConf) GetUsername() string                      { return "" }
+func (emptyRedisConf) GetDb() int32                             { return 0 }
+func (emptyRedisConf) GetClientName() string                    { return "" }
+func (emptyRedisConf) GetTlsCaFile() string                     { return "" }
+func (emptyRedisConf) GetTlsCertFile() string                   { return "" }
+func (emptyRedisConf) GetTlsKeyFile() string                    { return "" }
+func (emptyRedisConf) GetTlsServerName() string                 { return "" }
+func (emptyRedisConf) GetTlsInsecureSkipVerify() bool           { return false }
+func (emptyRedisConf) GetConnectMode() string                   { return "" }
+
+// redisTuning 由 RedisConf 与构造器默认值合并得到的最终参数
+type redisTuning struct {
+	poolSize        int
+	minIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+	dialTimeout     time.Duration
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	poolTimeout     time.Duration
+	lazy            bool
+}
+
+func resolveRedisTuning(conf RedisConf, poolTimeout time.Duration, lazy bool) redisTuning {
+	switch conf.GetConnectMode() {
+	case RedisConnectFailFast:
+		lazy = false
+	case RedisConnectLazy:
+		lazy = true
+	}
+	return redisTuning{
+		poolSize:        int(GetOrDefault(conf.GetPoolSize(), 50)),
+		minIdleConns:    int(GetOrDefault(conf.GetMinIdleConns(), 5)),
+		connMaxLifetime: durationOrDefault(conf.GetConnMaxLifetime(), 30*time.Minute),
+		connMaxIdleTime: durationOrDefault(conf.GetConnMaxIdleTime(), 5*time.Minute),
+		dialTimeout:     durationOrDefault(conf.GetDialTimeout(), 10*time.Second),
+		readTimeout:     durationOrDefault(conf.GetReadTimeout(), 10*time.Second),
+		writeTimeout:    durationOrDefault(conf.GetWriteTimeout(), 10*time.Second),
+		poolTimeout:     durationOrDefault(conf.GetPoolTimeout(), poolTimeout),
+		lazy:            lazy,
+	}
+}
+
+func durationOrDefault(d *durationpb.Duration, def time.Duration) time.Duration {
+	if d == nil || d.AsDuration() <= 0 {
+		return def
+	}
+	return d.AsDuration()
+}
+
+// buildRedisTLS 根据证书文件构建 tls.Config；base 非空时在其基础上修改（如 rediss:// DSN 已生成的配置）。
+// 未配置任何 TLS 参数时原样返回 base。
+func buildRedisTLS(conf RedisConf, base *tls.Config) (*tls.Config, error) {
+	if conf.GetTlsCaFile() == "" && conf.GetTlsCertFile() == "" && conf.GetTlsKeyFile() == "" &&
+		conf.GetTlsServerName() == "" && !conf.GetTlsInsecureSkipVerify() {
+		return base, nil
+	}
+
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		tc = base.Clone()
+	}
+	if conf.GetTlsServerName() != "" {
+		tc.ServerName = conf.GetTlsServerName()
+	}
+	tc.InsecureSkipVerify = conf.GetTlsInsecureSkipVerify() //nolint:gosec
+
+	if ca := conf.GetTlsCaFile(); ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, errors.WithMessage(err, "read redis tls ca file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no valid certificate in redis tls ca file %q", ca)
+		}
+		tc.RootCAs = pool
+	}
+	if conf.GetTlsCertFile() != "" || conf.GetTlsKeyFile() != "" {
+		cert, err := tls.LoadX509KeyPair(conf.GetTlsCertFile(), conf.GetTlsKeyFile())
+		if err != nil {
+			return nil, errors.WithMessage(err, "load redis tls client cert")
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
 func NewRedisCluster(
 	rootCtx context.Context,
 	mLogger log.Logger,
@@ -17,31 +148,78 @@ func NewRedisCluster(
 	passwd string,
 	readOnly bool,
 ) (*redis.ClusterClient, func(), error) {
-	hl := log.NewHelper(log.With(mLogger, "module", "redis"))
+	return NewRedisClusterWithConf(rootCtx, mLogger, seeds, passwd, readOnly, nil)
+}
 
+// NewRedisClusterWithConf 按 RedisConf 创建集群客户端；conf 为 nil 时等同 NewRedisCluster
+func NewRedisClusterWithConf(
+	rootCtx context.Context,
+	mLogger log.Logger,
+	seeds []string,
+	passwd string,
+	readOnly bool,
+	conf RedisConf,
+) (*redis.ClusterClient, func(), error) {
 	if len(seeds) == 0 {
 		return nil, nil, fmt.Errorf("redis.seeds is empty")
 	}
+	return newRedisCluster(rootCtx, mLogger, &redis.ClusterOptions{
+		Addrs:    seeds,
+		Password: passwd,
+		ReadOnly: readOnly,
+	}, conf)
+}
+
+// newRedisCluster 在 co（地址 / 认证等）基础上应用 RedisConf 并创建集群客户端
+func newRedisCluster(
+	rootCtx context.Context,
+	mLogger log.Logger,
+	co *redis.ClusterOptions,
+	conf RedisConf,
+) (*redis.ClusterClient, func(), error) {
+	hl := log.NewHelper(log.With(mLogger, "module", "redis"))
+
+	if conf == nil {
+		conf = emptyRedisConf{}
+	}
+	tlsConf, err := buildRedisTLS(conf, co.TLSConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	tn := resolveRedisTuning(conf, 10*time.Second, true)
 
-	co := &redis.ClusterOptions{
-		Addrs:                 seeds,
-		Password:              passwd,
-		ReadOnly:              readOnly,
-		PoolSize:              50,
-		MinIdleConns:          5,
-		ConnMaxLifetime:       30 * time.Minute,
-		ConnMaxIdleTime:       5 * time.Minute,
-		DialTimeout:           10 * time.Second,
-		ReadTimeout:           10 * time.Second,
-		WriteTimeout:          10 * time.Second,
-		PoolTimeout:           10 * time.Second,
-		ContextTimeoutEnabled: true,
+	co.TLSConfig = tlsConf
+	co.PoolSize = tn.poolSize
+	co.MinIdleConns = tn.minIdleConns
+	co.ConnMaxLifetime = tn.connMaxLifetime
+	co.ConnMaxIdleTime = tn.connMaxIdleTime
+	co.DialTimeout = tn.dialTimeout
+	co.ReadTimeout = tn.readTimeout
+	co.WriteTimeout = tn.writeTimeout
+	co.PoolTimeout = tn.poolTimeout
+	co.ContextTimeoutEnabled = true
+	if conf.GetUsername() != "" {
+		co.Username = conf.GetUsername()
+	}
+	if conf.GetClientName() != "" {
+		co.ClientName = conf.GetClientName()
 	}
+
 	client := redis.NewClusterClient(co)
 	cleanup := func() {
 		CloseQuietly(client)
 	}
 
+	if !tn.lazy {
+		ctx, cancel := context.WithTimeout(rootCtx, tn.dialTimeout+2*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			return nil, cleanup, errors.WithMessage(err, "redis cluster ping failed:")
+		}
+		hl.Infof("Connected to redis cluster seeds=%v readonly=%v", co.Addrs, co.ReadOnly)
+		return client, cleanup, nil
+	}
+
 	// 非致命探活：只打日志，不影响启动
 	go func() {
 		ctx, cancel := context.WithTimeout(rootCtx, 2*time.Second)
@@ -58,27 +236,40 @@ func NewRedisCluster(
 }
 
 func NewRedis(rootCtx context.Context, mLogger log.Logger, dsn string) (*redis.Client, func(), error) {
-	hl := log.NewHelper(log.With(mLogger, "module", "redis"))
+	return NewRedisWithConf(rootCtx, mLogger, dsn, nil)
+}
+
+// NewRedisWithConf 按 RedisConf 创建单机客户端；conf 为 nil 时等同 NewRedis
+func NewRedisWithConf(rootCtx context.Context, mLogger log.Logger, dsn string, conf RedisConf) (*redis.Client, func(), error) {
 	opts, err := redis.ParseURL(dsn)
 	if err != nil {
 		return nil, nil, fmt.Errorf("invalid redis DSN: %w", err)
 	}
+	return newRedis(rootCtx, mLogger, opts, conf)
+}
 
-	opts.PoolSize = 50                      // 最大连接数
-	opts.MinIdleConns = 5                   // 最小空闲连接数
-	opts.ConnMaxLifetime = 30 * time.Minute // 连接最大存活时间
-	opts.ConnMaxIdleTime = 5 * time.Minute  // 空闲连接超时释放
-	opts.DialTimeout = 10 * time.Second     // 建立连接超时
-	opts.ReadTimeout = 10 * time.Second     // 读超时
-	opts.WriteTimeout = 10 * time.Second    // 写超时
-	opts.PoolTimeout = 20 * time.Second     // 获取连接最大等待时间
+// newRedis 在 opts（地址 / 认证等）基础上应用 RedisConf 并创建单机客户端
+func newRedis(rootCtx context.Context, mLogger log.Logger, opts *redis.Options, conf RedisConf) (*redis.Client, func(), error) {
+	hl := log.NewHelper(log.With(mLogger, "module", "redis"))
+	if conf == nil {
+		conf = emptyRedisConf{}
+	}
+	if err := applyRedisConf(opts, conf); err != nil {
+		return nil, nil, err
+	}
+	lazy := resolveRedisTuning(conf, 0, false).lazy
 
 	client := redis.NewClient(opts)
 	cleanup := func() {
 		CloseQuietly(client)
 	}
 
-	ctx, cancel := context.WithTimeout(rootCtx, 12*time.Second)
+	if lazy {
+		hl.Infof("Redis client created @ %s db=%d (lazy connect)", opts.Addr, opts.DB)
+		return client, cleanup, nil
+	}
+
+	ctx, cancel := context.WithTimeout(rootCtx, opts.DialTimeout+2*time.Second)
 	defer cancel()
 	if err := client.Ping(ctx).Err(); err != nil {
 		return nil, cleanup, errors.WithMessage(err, "redis ping failed:")
@@ -87,3 +278,32 @@ func NewRedis(rootCtx context.Context, mLogger log.Logger, dsn string) (*redis.C
 	hl.Infof("Connected to redis @ %s db=%d", opts.Addr, opts.DB)
 	return client, cleanup, nil
 }
+
+func applyRedisConf(opts *redis.Options, conf RedisConf) error {
+	tlsConf, err := buildRedisTLS(conf, opts.TLSConfig)
+	if err != nil {
+		return err
+	}
+	tn := resolveRedisTuning(conf, 20*time.Second, false)
+
+	opts.PoolSize = tn.poolSize               // 最大连接数
+	opts.MinIdleConns = tn.minIdleConns       // 最小空闲连接数
+	opts.ConnMaxLifetime = tn.connMaxLifetime // 连接最大存活时间
+	opts.ConnMaxIdleTime = tn.connMaxIdleTime // 空闲连接超时释放
+	opts.DialTimeout = tn.dialTimeout         // 建立连接超时
+	opts.ReadTimeout = tn.readTimeout         // 读超时
+	opts.WriteTimeout = tn.writeTimeout       // 写超时
+	opts.PoolTimeout = tn.poolTimeout         // 获取连接最大等待时间
+	opts.TLSConfig = tlsConf
+
+	if conf.GetUsername() != "" {
+		opts.Username = conf.GetUsername()
+	}
+	if conf.GetDb() != 0 {
+		opts.DB = int(conf.GetDb())
+	}
+	if conf.GetClientName() != "" {
+		opts.ClientName = conf.GetClientName()
+	}
+	return nil
+}
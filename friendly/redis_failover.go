@@ -0,0 +1,196 @@
+package friendly
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	RedisSchemeCluster        = "redis+cluster"   // 集群，如 redis+cluster://:pwd@h1:6379?addr=h2:6379&read_only=true
+	RedisSchemeClusterTLS     = "rediss+cluster"  // 集群 + TLS
+	RedisSchemeSentinel       = "redis+sentinel"  // 哨兵，如 redis+sentinel://:pwd@s1:26379,s2:26379/mymaster/0?sentinel_password=x
+	RedisSchemeSentinelTLS    = "rediss+sentinel" // 哨兵 + TLS
+	defaultSentinelMasterName = "mymaster"
+)
+
+// NewRedisFailover 创建基于 Sentinel 的主从自动切换客户端。
+// sentinelPasswd 为哨兵节点的密码，passwd 为数据节点密码；DB / 用户名等取自 conf（可为 nil）。
+// 默认 fail fast：启动时 Ping 失败直接返回错误。
+func NewRedisFailover(
+	rootCtx context.Context,
+	mLogger log.Logger,
+	masterName string,
+	sentinelAddrs []string,
+	sentinelPasswd string,
+	passwd string,
+	conf RedisConf,
+) (redis.UniversalClient, func(), error) {
+	if masterName == "" {
+		return nil, nil, fmt.Errorf("redis.master_name is empty")
+	}
+	if len(sentinelAddrs) == 0 {
+		return nil, nil, fmt.Errorf("redis.sentinel_addrs is empty")
+	}
+	return newRedisFailover(rootCtx, mLogger, &redis.FailoverOptions{
+		MasterName:       masterName,
+		SentinelAddrs:    sentinelAddrs,
+		SentinelPassword: sentinelPasswd,
+		Password:         passwd,
+	}, conf)
+}
+
+func newRedisFailover(
+	rootCtx context.Context,
+	mLogger log.Logger,
+	fo *redis.FailoverOptions,
+	conf RedisConf,
+) (redis.UniversalClient, func(), error) {
+	hl := log.NewHelper(log.With(mLogger, "module", "redis"))
+	if conf == nil {
+		conf = emptyRedisConf{}
+	}
+	tlsConf, err := buildRedisTLS(conf, fo.TLSConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	tn := resolveRedisTuning(conf, 20*time.Second, false)
+
+	fo.TLSConfig = tlsConf
+	fo.PoolSize = tn.poolSize
+	fo.MinIdleConns = tn.minIdleConns
+	fo.ConnMaxLifetime = tn.connMaxLifetime
+	fo.ConnMaxIdleTime = tn.connMaxIdleTime
+	fo.DialTimeout = tn.dialTimeout
+	fo.ReadTimeout = tn.readTimeout
+	fo.WriteTimeout = tn.writeTimeout
+	fo.PoolTimeout = tn.poolTimeout
+	if conf.GetUsername() != "" {
+		fo.Username = conf.GetUsername()
+	}
+	if conf.GetDb() != 0 {
+		fo.DB = int(conf.GetDb())
+	}
+	if conf.GetClientName() != "" {
+		fo.ClientName = conf.GetClientName()
+	}
+
+	client := redis.NewFailoverClient(fo)
+	cleanup := func() {
+		CloseQuietly(client)
+	}
+
+	if tn.lazy {
+		hl.Infof("Redis failover client created master=%s sentinels=%v db=%d (lazy connect)",
+			fo.MasterName, fo.SentinelAddrs, fo.DB)
+		return client, cleanup, nil
+	}
+
+	ctx, cancel := context.WithTimeout(rootCtx, tn.dialTimeout+2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, cleanup, errors.WithMessage(err, "redis failover ping failed:")
+	}
+
+	hl.Infof("Connected to redis master=%s sentinels=%v db=%d", fo.MasterName, fo.SentinelAddrs, fo.DB)
+	return client, cleanup, nil
+}
+
+// NewRedisUniversal 根据 DSN scheme 选择客户端类型：
+//   - redis:// / rediss:// / unix://                 单机（同 NewRedisWithConf）
+//   - redis+cluster:// / rediss+cluster://           集群，额外节点用 addr= 参数（同 redis.ParseClusterURL）
+//   - redis+sentinel:// / rediss+sentinel://         哨兵，host 为逗号分隔的哨兵地址，path 为 /<master>[/<db>]
+//
+// conf 可为 nil；返回的 redis.UniversalClient 可直接用于 RedisLeader / RedisMutex 等。
+func NewRedisUniversal(
+	rootCtx context.Context,
+	mLogger log.Logger,
+	dsn string,
+	conf RedisConf,
+) (redis.UniversalClient, func(), error) {
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid redis DSN: missing scheme")
+	}
+
+	switch scheme {
+	case RedisSchemeCluster, RedisSchemeClusterTLS:
+		co, err := redis.ParseClusterURL(strings.TrimSuffix(scheme, "+cluster") + "://" + rest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid redis cluster DSN: %w", err)
+		}
+		c, cleanup, err := newRedisCluster(rootCtx, mLogger, co, conf)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return c, cleanup, nil
+
+	case RedisSchemeSentinel, RedisSchemeSentinelTLS:
+		fo, err := parseSentinelURL(dsn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid redis sentinel DSN: %w", err)
+		}
+		return newRedisFailover(rootCtx, mLogger, fo, conf)
+
+	default:
+		// 避免将 nil *redis.Client 包装成非 nil 的接口值
+		c, cleanup, err := NewRedisWithConf(rootCtx, mLogger, dsn, conf)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return c, cleanup, nil
+	}
+}
+
+// parseSentinelURL 解析 redis+sentinel://[user:pass@]s1:26379,s2:26379/<master>[/<db>]?sentinel_password=x&sentinel_username=y
+func parseSentinelURL(dsn string) (*redis.FailoverOptions, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	fo := &redis.FailoverOptions{MasterName: defaultSentinelMasterName}
+	for _, addr := range strings.Split(u.Host, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			fo.SentinelAddrs = append(fo.SentinelAddrs, addr)
+		}
+	}
+	if len(fo.SentinelAddrs) == 0 {
+		return nil, errors.New("sentinel addrs is empty")
+	}
+
+	if u.User != nil {
+		fo.Username = u.User.Username()
+		fo.Password, _ = u.User.Password()
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) > 2 {
+		return nil, errors.Errorf("unexpected path %q, expect /<master>[/<db>]", u.Path)
+	}
+	if parts[0] != "" {
+		fo.MasterName = parts[0]
+	}
+	if len(parts) == 2 {
+		if fo.DB, err = strconv.Atoi(parts[1]); err != nil {
+			return nil, errors.Errorf("invalid db %q", parts[1])
+		}
+	}
+
+	q := u.Query()
+	fo.SentinelUsername = q.Get("sentinel_username")
+	fo.SentinelPassword = q.Get("sentinel_password")
+
+	if u.Scheme == RedisSchemeSentinelTLS {
+		fo.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return fo, nil
+}
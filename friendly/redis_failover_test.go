@@ -0,0 +1,126 @@
+package friendly
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestParseSentinelURL(t *testing.T) {
+	t.Run("full dsn", func(t *testing.T) {
+		fo, err := parseSentinelURL("rediss+sentinel://user:pwd@s1:26379,s2:26379/cache/2?sentinel_password=sp&sentinel_username=su")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(fo.SentinelAddrs, []string{"s1:26379", "s2:26379"}) {
+			t.Fatalf("unexpected sentinel addrs: %v", fo.SentinelAddrs)
+		}
+		if fo.MasterName != "cache" || fo.DB != 2 || fo.Username != "user" || fo.Password != "pwd" {
+			t.Fatalf("unexpected options: %+v", fo)
+		}
+		if fo.SentinelPassword != "sp" || fo.SentinelUsername != "su" {
+			t.Fatalf("unexpected sentinel auth: %s/%s", fo.SentinelUsername, fo.SentinelPassword)
+		}
+		if fo.TLSConfig == nil {
+			t.Fatal("expected tls config for rediss+sentinel")
+		}
+	})
+
+	t.Run("default master name", func(t *testing.T) {
+		fo, err := parseSentinelURL("redis+sentinel://s1:26379")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fo.MasterName != defaultSentinelMasterName || fo.DB != 0 || fo.TLSConfig != nil {
+			t.Fatalf("unexpected options: %+v", fo)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, dsn := range []string{
+			"redis+sentinel:///mymaster",
+			"redis+sentinel://s1:26379/mymaster/x",
+			"redis+sentinel://s1:26379/a/0/b",
+		} {
+			if _, err := parseSentinelURL(dsn); err == nil {
+				t.Fatalf("expected error for %q", dsn)
+			}
+		}
+	})
+}
+
+func TestNewRedisFailover(t *testing.T) {
+	if _, _, err := NewRedisFailover(context.Background(), log.DefaultLogger, "", []string{"s1:26379"}, "", "", nil); err == nil {
+		t.Fatal("expected error for empty master name")
+	}
+	if _, _, err := NewRedisFailover(context.Background(), log.DefaultLogger, "mymaster", nil, "", "", nil); err == nil {
+		t.Fatal("expected error for empty sentinel addrs")
+	}
+}
+
+func TestNewRedisUniversal(t *testing.T) {
+	ctx := context.Background()
+	fast := testRedisConf{dialTimeout: durationpb.New(100 * time.Millisecond)}
+
+	t.Run("missing scheme", func(t *testing.T) {
+		if _, _, err := NewRedisUniversal(ctx, log.DefaultLogger, "127.0.0.1:6379", nil); err == nil {
+			t.Fatal("expected error for dsn without scheme")
+		}
+	})
+
+	t.Run("cluster is lazy by default", func(t *testing.T) {
+		c, cleanup, err := NewRedisUniversal(ctx, log.DefaultLogger, "redis+cluster://:pwd@127.0.0.1:1?addr=127.0.0.1:2", fast)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer cleanup()
+		cc, ok := c.(*redis.ClusterClient)
+		if !ok {
+			t.Fatalf("expected cluster client, got %T", c)
+		}
+		if len(cc.Options().Addrs) != 2 || cc.Options().Password != "pwd" {
+			t.Fatalf("unexpected cluster options: %+v", cc.Options())
+		}
+	})
+
+	t.Run("sentinel fails fast", func(t *testing.T) {
+		_, cleanup, err := NewRedisUniversal(ctx, log.DefaultLogger, "redis+sentinel://127.0.0.1:1/mymaster", fast)
+		if err == nil {
+			t.Fatal("expected ping error")
+		}
+		if cleanup != nil {
+			cleanup()
+		}
+	})
+
+	t.Run("standalone", func(t *testing.T) {
+		c, cleanup, err := NewRedisUniversal(ctx, log.DefaultLogger, "redis://127.0.0.1:1/0",
+			testRedisConf{mode: RedisConnectLazy})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer cleanup()
+		if _, ok := c.(*redis.Client); !ok {
+			t.Fatalf("expected standalone client, got %T", c)
+		}
+	})
+}
+
+func TestNewRedisUniversalNilOnError(t *testing.T) {
+	c, cleanup, err := NewRedisUniversal(context.Background(), log.DefaultLogger, "redis://127.0.0.1:1/0",
+		testRedisConf{dialTimeout: durationpb.New(100 * time.Millisecond)})
+	if err == nil {
+		t.Fatal("expected ping error")
+	}
+	if cleanup != nil {
+		cleanup()
+	}
+	if c != nil {
+		t.Fatalf("expected nil interface on error, got %T", c)
+	}
+}
@@ -0,0 +1,206 @@
+package friendly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+type CtxKey string
+
+// FlagDisableRedisLog ctx 中携带该 key（任意非 nil 值）时，RedisHook 不输出该请求的慢命令 / 错误日志，统计照常
+const FlagDisableRedisLog CtxKey = "disable_redis_log"
+
+var _ redis.Hook = (*RedisHook)(nil)
+
+// DefaultRedisLatencyBuckets 默认的延迟直方图上界，超出最后一个上界的计入溢出桶
+var DefaultRedisLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// RedisHookOption RedisHook 的可选参数
+type RedisHookOption func(h *RedisHook)
+
+// WithRedisSlowThreshold 慢命令阈值，默认 100ms；<=0 时不输出慢日志
+func WithRedisSlowThreshold(d time.Duration) RedisHookOption {
+	return func(h *RedisHook) { h.slowThreshold = d }
+}
+
+// WithRedisMaxArgLen 日志中单个参数的最大长度，超出时只保留长度信息，默认 64
+func WithRedisMaxArgLen(n int) RedisHookOption {
+	return func(h *RedisHook) { h.maxArgLen = n }
+}
+
+// WithRedisLatencyBuckets 自定义延迟直方图上界（需升序）
+func WithRedisLatencyBuckets(buckets []time.Duration) RedisHookOption {
+	return func(h *RedisHook) { h.buckets = buckets }
+}
+
+// RedisCommandStats 单个命令的累计统计
+type RedisCommandStats struct {
+	Count   int64
+	Errors  int64
+	Total   time.Duration
+	Bounds  []time.Duration // 直方图上界
+	Buckets []int64         // 与 Bounds 对应，最后多一个溢出桶
+}
+
+// RedisHook 可选挂载的 redis.Hook：
+// - 超过阈值的慢命令 / 出错命令通过 kratos log.Logger 输出（redis.Nil 不视为错误）
+// - 按命令名统计次数、错误数与延迟直方图，通过 Stats 读取快照；管道（含 TxPipelined）中的命令同样按命令名统计，
+// 另以 "pipeline" 汇总整个管道
+// - 日志中超长参数只输出长度，避免大 value / 敏感数据落盘
+//
+// 用法：client.AddHook(friendly.NewRedisHook(logger))
+type RedisHook struct {
+	log           *log.Helper
+	slowThreshold time.Duration
+	maxArgLen     int
+	buckets       []time.Duration
+
+	mu    sync.Mutex
+	stats map[string]*RedisCommandStats
+}
+
+func NewRedisHook(logger log.Logger, opts ...RedisHookOption) *RedisHook {
+	h := &RedisHook{
+		log:           log.NewHelper(log.With(logger, "module", "redis")),
+		slowThreshold: 100 * time.Millisecond,
+		maxArgLen:     64,
+		buckets:       DefaultRedisLatencyBuckets,
+		stats:         make(map[string]*RedisCommandStats),
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		begin := time.Now()
+		err := next(ctx, cmd)
+		h.observe(ctx, cmd.Name(), []redis.Cmder{cmd}, time.Since(begin), err)
+		return err
+	}
+}
+
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		begin := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(begin)
+		// 管道内每个命令按自身结果计入各自的统计（延迟取整个管道的往返耗时），另记一条 pipeline 汇总
+		for _, cmd := range cmds {
+			h.record(cmd.Name(), elapsed, isRedisFailure(cmd.Err()))
+		}
+		h.observe(ctx, "pipeline", cmds, elapsed, err)
+		return err
+	}
+}
+
+// Stats 返回按命令名统计的快照
+func (h *RedisHook) Stats() map[string]RedisCommandStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(map[string]RedisCommandStats, len(h.stats))
+	for name, st := range h.stats {
+		cp := *st
+		cp.Buckets = append([]int64(nil), st.Buckets...)
+		out[name] = cp
+	}
+	return out
+}
+
+func (h *RedisHook) observe(ctx context.Context, name string, cmds []redis.Cmder, elapsed time.Duration, err error) {
+	failed := isRedisFailure(err)
+	h.record(name, elapsed, failed)
+
+	if ctx.Value(FlagDisableRedisLog) != nil {
+		return
+	}
+	ms := float64(elapsed.Nanoseconds()) / 1e6
+	switch {
+	case failed:
+		h.log.Errorf("[%.3fms] %s err=%v", ms, h.formatCmds(cmds), err)
+	case h.slowThreshold > 0 && elapsed >= h.slowThreshold:
+		h.log.Warnf("SLOW REDIS >= %v [%.3fms] %s", h.slowThreshold, ms, h.formatCmds(cmds))
+	}
+}
+
+// isRedisFailure redis.Nil（key 不存在）不视为错误
+func isRedisFailure(err error) bool {
+	return err != nil && !errors.Is(err, redis.Nil)
+}
+
+func (h *RedisHook) record(name string, elapsed time.Duration, failed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st, ok := h.stats[name]
+	if !ok {
+		st = &RedisCommandStats{Bounds: h.buckets, Buckets: make([]int64, len(h.buckets)+1)}
+		h.stats[name] = st
+	}
+	st.Count++
+	st.Total += elapsed
+	if failed {
+		st.Errors++
+	}
+	idx := len(h.buckets)
+	for i, b := range h.buckets {
+		if elapsed <= b {
+			idx = i
+			break
+		}
+	}
+	st.Buckets[idx]++
+}
+
+func (h *RedisHook) formatCmds(cmds []redis.Cmder) string {
+	parts := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		parts = append(parts, h.formatArgs(cmd.Args()))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// formatArgs 拼接命令参数，超过 maxArgLen 的参数以 <len:N> 代替
+func (h *RedisHook) formatArgs(args []interface{}) string {
+	var b strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			s = fmt.Sprint(v)
+		}
+		if h.maxArgLen > 0 && len(s) > h.maxArgLen {
+			s = fmt.Sprintf("<len:%d>", len(s))
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package friendly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+type capturedLogger struct {
+	entries []string
+	levels  []log.Level
+}
+
+func (l *capturedLogger) Log(level log.Level, keyvals ...interface{}) error {
+	l.levels = append(l.levels, level)
+	l.entries = append(l.entries, fmt.Sprint(keyvals...))
+	return nil
+}
+
+func TestRedisHookProcess(t *testing.T) {
+	base := &capturedLogger{}
+	h := NewRedisHook(base, WithRedisSlowThreshold(10*time.Millisecond), WithRedisMaxArgLen(4))
+
+	slow := h.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		time.Sleep(15 * time.Millisecond)
+		return nil
+	})
+	failed := h.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return errors.New("boom")
+	})
+	miss := h.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return redis.Nil
+	})
+
+	ctx := context.Background()
+	_ = slow(ctx, redis.NewStatusCmd(ctx, "set", "k", "a-very-long-value"))
+	_ = failed(ctx, redis.NewStringCmd(ctx, "get", "k"))
+	_ = miss(ctx, redis.NewStringCmd(ctx, "get", "k"))
+
+	if len(base.entries) != 2 {
+		t.Fatalf("expected slow + error logs, got %v", base.entries)
+	}
+	if base.levels[0] != log.LevelWarn || !strings.Contains(base.entries[0], "SLOW REDIS") {
+		t.Fatalf("unexpected slow log: %s", base.entries[0])
+	}
+	if !strings.Contains(base.entries[0], "set k <len:17>") {
+		t.Fatalf("long arg should be redacted: %s", base.entries[0])
+	}
+	if base.levels[1] != log.LevelError || !strings.Contains(base.entries[1], "boom") {
+		t.Fatalf("unexpected error log: %s", base.entries[1])
+	}
+
+	stats := h.Stats()
+	if stats["set"].Count != 1 || stats["get"].Count != 2 || stats["get"].Errors != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	var observed int64
+	for i, n := range stats["set"].Buckets {
+		if i < 2 && n != 0 {
+			t.Fatalf("15ms command should not land in <=5ms buckets: %+v", stats["set"].Buckets)
+		}
+		observed += n
+	}
+	if observed != 1 {
+		t.Fatalf("unexpected histogram: %+v", stats["set"].Buckets)
+	}
+}
+
+func TestRedisHookDisableLog(t *testing.T) {
+	base := &capturedLogger{}
+	h := NewRedisHook(base)
+	pipe := h.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+		return errors.New("boom")
+	})
+
+	ctx := context.WithValue(context.Background(), FlagDisableRedisLog, true)
+	_ = pipe(ctx, []redis.Cmder{redis.NewStringCmd(ctx, "get", "a"), redis.NewStringCmd(ctx, "get", "b")})
+
+	if len(base.entries) != 0 {
+		t.Fatalf("expected no logs when disabled, got %v", base.entries)
+	}
+	if st := h.Stats()["pipeline"]; st.Count != 1 || st.Errors != 1 {
+		t.Fatalf("stats should still be recorded: %+v", st)
+	}
+}
+
+func TestRedisHookPipelinePerCommand(t *testing.T) {
+	h := NewRedisHook(&capturedLogger{})
+	pipe := h.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+		cmds[1].SetErr(redis.Nil)
+		cmds[2].SetErr(errors.New("WRONGTYPE"))
+		return cmds[2].Err()
+	})
+
+	ctx := context.Background()
+	_ = pipe(ctx, []redis.Cmder{
+		redis.NewStatusCmd(ctx, "set", "a", "1"),
+		redis.NewStringCmd(ctx, "get", "b"),
+		redis.NewIntCmd(ctx, "incr", "c"),
+	})
+
+	stats := h.Stats()
+	if st := stats["pipeline"]; st.Count != 1 || st.Errors != 1 {
+		t.Fatalf("pipeline stats: %+v", st)
+	}
+	if st := stats["set"]; st.Count != 1 || st.Errors != 0 {
+		t.Fatalf("set stats: %+v", st)
+	}
+	if st := stats["get"]; st.Count != 1 || st.Errors != 0 {
+		t.Fatalf("redis.Nil should not count as error: %+v", st)
+	}
+	if st := stats["incr"]; st.Count != 1 || st.Errors != 1 {
+		t.Fatalf("incr stats: %+v", st)
+	}
+}
+
+func TestRedisHookTxPipelined(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	h := NewRedisHook(&capturedLogger{})
+	rdb.AddHook(h)
+
+	ctx := context.Background()
+	if _, err := rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, "a", "1", 0)
+		p.Incr(ctx, "a")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 建连时的 hello / client 命令同样经过管道钩子，这里只检查事务内的命令
+	stats := h.Stats()
+	for _, name := range []string{"multi", "set", "incr", "exec"} {
+		if st := stats[name]; st.Count != 1 || st.Errors != 0 {
+			t.Fatalf("%s stats: %+v", name, st)
+		}
+	}
+}
@@ -2,35 +2,30 @@ package friendly
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
+	"strconv"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
 )
 
-// RedisLeader 通过 Redis 实现单点执行的领导者选举：
-// - 以 SET NX PX 获取锁（TTL=ttl）
-// - 周期性校验并续期（仅当 value 匹配本实例 id 时）
-// - 丢失领导权后自动退出工作回调并重试抢占
-// - Stop 时安全释放（compare-and-del）
+// ErrStaleEpoch 调用方持有的 epoch 已不是最新（已有新 leader 上任），写入被拒绝
+var ErrStaleEpoch = errors.New("leader epoch is stale")
+
+var _ LeaseStore = (*RedisLeaseStore)(nil)
+
+// RedisLeader 通过 Redis 实现单点执行的领导者选举，选举循环见 LeaseLeader：
+// - 以 SET NX PX 获取锁（TTL=ttl），同时 INCR 与锁同 slot 的 epoch key 得到 fencing token
+// - 续期 / 释放均为 compare-and-xxx，仅当 value 匹配本实例 id 时生效
+// - FencedEval 可在写入时校验 epoch，拒绝已下台的旧 leader
 // 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
 type RedisLeader struct {
-	rdb        redis.Cmdable
-	key        string
-	id         string
-	ttl        time.Duration
-	renewEvery time.Duration
-	logger     *log.Helper
-	onStarted  func(ctx context.Context)
-	onStopped  func(context.Context)
-
-	running atomic.Bool
-	cancel  context.CancelFunc
-	wg      sync.WaitGroup
+	*LeaseLeader
+	epochKey string
+	rdb      redis.Cmdable
 }
 
 // NewRedisLeader：单机版构造器
@@ -57,6 +52,18 @@ func NewRedisClusterLeader(
 	return newRedisLeader(rdb, key, ttl, renewEvery, logger, onStarted, onStopped)
 }
 
+// NewRedisUniversalLeader：通用构造器，适用于 NewRedisUniversal / NewRedisFailover 返回的客户端
+func NewRedisUniversalLeader(
+	rdb redis.UniversalClient,
+	key string,
+	ttl, renewEvery time.Duration,
+	logger log.Logger,
+	onStarted func(context.Context),
+	onStopped func(context.Context),
+) *RedisLeader {
+	return newRedisLeader(rdb, key, ttl, renewEvery, logger, onStarted, onStopped)
+}
+
 func newRedisLeader(
 	rdb redis.Cmdable,
 	key string,
@@ -65,150 +72,49 @@ func newRedisLeader(
 	onStarted func(context.Context),
 	onStopped func(context.Context),
 ) *RedisLeader {
-	if renewEvery <= 0 || (ttl > 0 && renewEvery >= ttl) {
-		renewEvery = ttl / 3
-	}
 	return &RedisLeader{
-		rdb:        rdb,
-		key:        key,
-		id:         randID(),
-		ttl:        ttl,
-		renewEvery: renewEvery,
-		logger:     log.NewHelper(log.With(logger, "module", "leader/redis")),
-		onStarted:  onStarted,
-		onStopped:  onStopped,
-	}
-}
-
-// Start 启动领导者循环：抢占 → 续期 → 丢失后重试
-func (l *RedisLeader) Start(ctx context.Context) error {
-	if !l.running.CompareAndSwap(false, true) {
-		return nil
-	}
-	runCtx, cancel := context.WithCancel(ctx)
-	l.cancel = cancel
-
-	l.wg.Add(1)
-	go l.loop(runCtx)
-
-	l.logger.Infof("leader loop started, key=%s ttl=%s renew=%s id=%s",
-		l.key, l.ttl, l.renewEvery, l.id)
-	return nil
-}
-
-// Stop 停止并释放锁
-func (l *RedisLeader) Stop(ctx context.Context) error {
-	if !l.running.CompareAndSwap(true, false) {
-		return nil
-	}
-	if l.cancel != nil {
-		l.cancel()
-	}
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		l.wg.Wait()
-	}()
-
-	select {
-	case <-done:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-}
-
-func (l *RedisLeader) loop(ctx context.Context) {
-	defer l.wg.Done()
-
-	var (
-		isLeader   bool
-		workCancel context.CancelFunc
-	)
-
-	renewTicker := time.NewTicker(l.renewEvery)
-	defer renewTicker.Stop()
-
-	backoff := 300 * time.Millisecond
-
-	for {
-		if ctx.Err() != nil {
-			l.stopIfLeader(&isLeader, &workCancel, true) // ctx 退出：需要闭环 onStopped
-			return
-		}
-
-		if !isLeader {
-			ok, err := l.acquire(ctx)
-			if err != nil {
-				l.logger.Warnf("acquire error: %+v", err)
-				sleepJitter(ctx, backoff)
-				continue
-			}
-			if !ok {
-				sleepJitter(ctx, backoff)
-				continue
-			}
-
-			isLeader = true
-			workCancel = l.startWork(ctx)
-			continue
-		}
-
-		select {
-		case <-ctx.Done():
-			l.stopIfLeader(&isLeader, &workCancel, true)
-			return
-
-		case <-renewTicker.C:
-			ok, err := l.renew(ctx)
-			if err != nil {
-				// 出错不等于丢失领导权，下一轮再尝试
-				l.logger.Warnf("renew error: %+v", err)
-				continue
-			}
-			if !ok {
-				// 领导权被抢/丢失：停止工作并闭环 onStopped，但不 release（因为已不是我们的锁）
-				l.stopIfLeader(&isLeader, &workCancel, false)
-			}
-		}
+		LeaseLeader: newLeaseLeader(
+			NewRedisLeaseStore(rdb), "leader/redis", key, ttl, renewEvery, logger, onStarted, onStopped,
+		),
+		epochKey: siblingKey(key, "epoch"),
+		rdb:      rdb,
 	}
 }
 
-func (l *RedisLeader) startWork(parent context.Context) context.CancelFunc {
-	workCtx, cancel := context.WithCancel(parent)
-	if l.onStarted != nil {
-		go l.onStarted(workCtx)
-	}
-	return cancel
+// RedisLeaseStore 基于 Redis 的 LeaseStore：
+// 锁 key 存持有者 id，epoch / successor 存于同 slot 的辅助 key（见 siblingKey）
+type RedisLeaseStore struct {
+	rdb redis.Cmdable
 }
 
-// stopIfLeader：保证 onStarted/onStopped 闭环；releaseOnlyWhenOwned=true 时会尝试释放锁
-func (l *RedisLeader) stopIfLeader(isLeader *bool, workCancel *context.CancelFunc, releaseOnlyWhenOwned bool) {
-	if isLeader == nil || !*isLeader {
-		return
-	}
-	*isLeader = false
-
-	// 先 cancel，让工作尽快停，再释放锁，降低“新 leader 已开始但旧 worker 仍在跑”的窗口
-	if workCancel != nil && *workCancel != nil {
-		(*workCancel)()
-		*workCancel = nil
-	}
-
-	if l.onStopped != nil {
-		go l.onStopped(context.Background())
-	}
-
-	if releaseOnlyWhenOwned {
-		l.release(context.Background())
-	}
+func NewRedisLeaseStore(rdb redis.Cmdable) *RedisLeaseStore {
+	return &RedisLeaseStore{rdb: rdb}
 }
 
-func (l *RedisLeader) acquire(ctx context.Context) (bool, error) {
-	return l.rdb.SetNX(ctx, l.key, l.id, l.ttl).Result()
+// TryAcquire 抢占成功时返回新的 epoch（>0），未抢到返回 0
+func (s *RedisLeaseStore) TryAcquire(ctx context.Context, key, id string, ttl time.Duration) (int64, error) {
+	// SET NX PX 与 INCR epoch 在同一脚本内完成，保证每个任期拿到唯一且递增的 token；
+	// 存在 successor 提示且不是本实例时放弃抢占，由指定继任者优先接手
+	const lua = `
+local successor = redis.call('GET', KEYS[3])
+if successor and successor ~= ARGV[1] then
+  return 0
+end
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  if successor then
+    redis.call('DEL', KEYS[3])
+  end
+  return redis.call('INCR', KEYS[2])
+end
+return 0
+`
+	return s.rdb.Eval(ctx, lua,
+		[]string{key, siblingKey(key, "epoch"), siblingKey(key, "successor")},
+		id, int(ttl/time.Millisecond),
+	).Int64()
 }
 
-func (l *RedisLeader) renew(ctx context.Context) (bool, error) {
+func (s *RedisLeaseStore) Renew(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
 	// 仅当 value 匹配本实例 id 时续期
 	const lua = `
 if redis.call('GET', KEYS[1]) == ARGV[1] then
@@ -216,7 +122,7 @@ if redis.call('GET', KEYS[1]) == ARGV[1] then
 end
 return 0
 `
-	res, err := l.rdb.Eval(ctx, lua, []string{l.key}, l.id, int(l.ttl/time.Millisecond)).Result()
+	res, err := s.rdb.Eval(ctx, lua, []string{key}, id, int(ttl/time.Millisecond)).Result()
 	if err != nil {
 		return false, err
 	}
@@ -230,34 +136,144 @@ return 0
 	}
 }
 
-func (l *RedisLeader) release(ctx context.Context) {
-	// compare-and-del，避免误删他人锁
+// Release compare-and-del，避免误删他人锁；指定 Successor 时同时写入继任者提示（TTL=HintTTL）
+func (s *RedisLeaseStore) Release(ctx context.Context, key, id string, opt ReleaseOptions) error {
 	const lua = `
 if redis.call('GET', KEYS[1]) == ARGV[1] then
+  if ARGV[2] ~= '' then
+    redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+  end
   return redis.call('DEL', KEYS[1])
 end
 return 0
 `
-	_, _ = l.rdb.Eval(ctx, lua, []string{l.key}, l.id).Result()
+	_, err := s.rdb.Eval(ctx, lua,
+		[]string{key, siblingKey(key, "successor")},
+		id, opt.Successor, int(opt.HintTTL/time.Millisecond),
+	).Result()
+	return err
+}
+
+// Holder 返回当前持有锁的实例 id；无人持有时返回空串
+func (s *RedisLeaseStore) Holder(ctx context.Context, key string) (string, error) {
+	id, err := s.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return id, nil
+}
+
+// EpochKey 返回存放 epoch 的 key，与锁 key 位于同一 hash slot
+func (l *RedisLeader) EpochKey() string {
+	return l.epochKey
+}
+
+// FencedEval 仅当 epoch 仍是最新任期时执行 script，否则返回 ErrStaleEpoch。
+// script 的写法与普通 Eval 一致（KEYS/ARGV 从 1 开始），epoch 校验由包装层完成。
+// 集群模式下 keys 需与锁 key 使用相同的 hash tag（如 "{job}:leader" / "{job}:data"），否则会 CROSSSLOT。
+func (l *RedisLeader) FencedEval(
+	ctx context.Context,
+	epoch int64,
+	script string,
+	keys []string,
+	args ...interface{},
+) (interface{}, error) {
+	fullKeys := append([]string{l.epochKey}, keys...)
+	fullArgs := append([]interface{}{strconv.FormatInt(epoch, 10)}, args...)
+
+	res, err := l.rdb.Eval(ctx, fencedScript(script), fullKeys, fullArgs...).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), fencedErrMsg) {
+			return nil, ErrStaleEpoch
+		}
+		if errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+const fencedErrMsg = "STALE_EPOCH"
+
+// fencedScript 在用户脚本前插入 epoch 校验，并把 fencing 参数从 KEYS/ARGV 中移除
+func fencedScript(body string) string {
+	return `
+local fenceKey = table.remove(KEYS, 1)
+local fenceEpoch = table.remove(ARGV, 1)
+if redis.call('GET', fenceKey) ~= fenceEpoch then
+  return redis.error_reply('` + fencedErrMsg + `')
+end
+` + body
+}
+
+// siblingKey 生成与 key 同 slot 的辅助 key（epoch / successor 等）：
+// key 已带 hash tag 时直接追加后缀；key 不含 '}' 时用 {key} 作为 hash tag（slot 与原 key 相同）；
+// 否则（如 job:{}:leader，Redis Cluster 按整个 key 计算 slot）选取一个同 slot 且不含花括号的 hash tag
+func siblingKey(key, suffix string) string {
+	if _, ok := hashTag(key); ok {
+		return key + ":" + suffix
+	}
+	if !strings.Contains(key, "}") {
+		return "{" + key + "}:" + suffix
+	}
+	return "{" + slotTags()[keySlot(key)] + "}:" + key + ":" + suffix
 }
 
-func randID() string {
-	var b [16]byte
-	_, _ = rand.Read(b[:])
-	return hex.EncodeToString(b[:])
+// hashTag 按 Redis Cluster 规则提取 hash tag：第一个 '{' 与其后第一个 '}' 之间的非空内容
+func hashTag(key string) (string, bool) {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return "", false
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return "", false
+	}
+	return key[start+1 : start+1+end], true
 }
 
-func sleepJitter(ctx context.Context, d time.Duration) {
-	t := time.NewTimer(d + time.Duration(randByte()%250)*time.Millisecond)
-	defer t.Stop()
-	select {
-	case <-ctx.Done():
-	case <-t.C:
+const clusterSlots = 16384
+
+// keySlot 计算 key 在 Redis Cluster 中的 slot
+func keySlot(key string) int {
+	if tag, ok := hashTag(key); ok {
+		key = tag
 	}
+	return int(crc16(key)) % clusterSlots
 }
 
-func randByte() byte {
-	var b [1]byte
-	_, _ = rand.Read(b[:])
-	return b[0]
+// slotTags 每个 slot 对应一个不含花括号的 hash tag，首次使用时生成
+var slotTags = sync.OnceValue(func() *[clusterSlots]string {
+	var (
+		tags [clusterSlots]string
+		left = clusterSlots
+	)
+	for i := int64(0); left > 0; i++ {
+		tag := strconv.FormatInt(i, 36)
+		if slot := int(crc16(tag)) % clusterSlots; tags[slot] == "" {
+			tags[slot] = tag
+			left--
+		}
+	}
+	return &tags
+})
+
+// crc16 Redis Cluster 使用的 CRC16（XMODEM）
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
 }
@@ -0,0 +1,58 @@
+package friendly
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeySlot(t *testing.T) {
+	// 与 Redis CLUSTER KEYSLOT 的结果一致
+	cases := map[string]int{
+		"foo":          12182,
+		"bar":          5061,
+		"{foo}:leader": 12182,
+	}
+	for key, want := range cases {
+		if got := keySlot(key); got != want {
+			t.Fatalf("keySlot(%q)=%d want=%d", key, got, want)
+		}
+	}
+	if keySlot("{}foo") == keySlot("foo") {
+		t.Fatal("empty hash tag should hash the whole key")
+	}
+}
+
+func TestSiblingKey(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{in: "job:leader", want: "{job:leader}:epoch"},
+		{in: "{job}:leader", want: "{job}:leader:epoch"},
+		{in: "job:{x", want: "{job:{x}:epoch"},
+		{in: "job:{}:leader"},
+		{in: "job}:leader"},
+	}
+	for _, c := range cases {
+		got := siblingKey(c.in, "epoch")
+		if c.want != "" && got != c.want {
+			t.Fatalf("siblingKey(%q)=%q want=%q", c.in, got, c.want)
+		}
+		if keySlot(got) != keySlot(c.in) {
+			t.Fatalf("siblingKey(%q)=%q slot %d, key slot %d", c.in, got, keySlot(got), keySlot(c.in))
+		}
+		if !strings.HasSuffix(got, ":epoch") {
+			t.Fatalf("siblingKey(%q)=%q should keep suffix", c.in, got)
+		}
+	}
+}
+
+func TestFencedScript(t *testing.T) {
+	s := fencedScript("return redis.call('SET', KEYS[1], ARGV[1])")
+	if !strings.Contains(s, "table.remove(KEYS, 1)") || !strings.Contains(s, "table.remove(ARGV, 1)") {
+		t.Fatalf("fenced script should shift KEYS/ARGV: %s", s)
+	}
+	if !strings.HasSuffix(s, "return redis.call('SET', KEYS[1], ARGV[1])") {
+		t.Fatalf("fenced script should keep user body at the end: %s", s)
+	}
+}
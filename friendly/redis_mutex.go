@@ -0,0 +1,240 @@
+package friendly
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLockNotHeld 释放 / 续期时发现锁已不属于当前 owner（已过期或被他人持有）
+var ErrLockNotHeld = errors.New("lock not held")
+
+type lockOptions struct {
+	ttl    time.Duration
+	owner  string
+	retry  time.Duration
+	logger log.Logger
+}
+
+// LockOption RedisMutex / RedisSemaphore 的可选参数
+type LockOption func(o *lockOptions)
+
+// WithLockTTL 锁 / 许可的过期时间，持有期间由 watchdog 每 ttl/3 自动续期，默认 30s
+func WithLockTTL(ttl time.Duration) LockOption {
+	return func(o *lockOptions) { o.ttl = ttl }
+}
+
+// WithLockOwner 指定 owner id（默认随机），相同 owner 可重入同一把 RedisMutex
+func WithLockOwner(owner string) LockOption {
+	return func(o *lockOptions) { o.owner = owner }
+}
+
+// WithLockRetry Lock / Acquire 阻塞等待时的重试间隔（另加随机抖动），默认 100ms
+func WithLockRetry(d time.Duration) LockOption {
+	return func(o *lockOptions) { o.retry = d }
+}
+
+// WithLockLogger 设置日志输出，默认 log.GetLogger()
+func WithLockLogger(logger log.Logger) LockOption {
+	return func(o *lockOptions) { o.logger = logger }
+}
+
+func newLockOptions(opts []LockOption) lockOptions {
+	o := lockOptions{
+		ttl:   30 * time.Second,
+		retry: 100 * time.Millisecond,
+	}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if o.owner == "" {
+		o.owner = randID()
+	}
+	if o.logger == nil {
+		o.logger = log.GetLogger()
+	}
+	return o
+}
+
+// RedisMutex 基于 Redis 的短期分布式互斥锁：
+// - 锁为 hash：field=owner，value=重入次数；仅 owner 匹配时续期 / 释放（compare-and-renew / compare-and-del）
+// - 同一 owner 可重入，Unlock 次数与 Lock 次数相同时才真正删除
+// - 持有期间 watchdog 自动续期，进程崩溃时锁在 ttl 后自然过期
+// - 续期 / 释放与 RedisLeaseStore 的 Renew / Release 同为“先比较 owner 再操作”的单脚本，
+// 但租约 key 是只存 id 的字符串，无法记录重入次数，因此这里以 hash 存 owner -> 次数，脚本改用 HEXISTS / HINCRBY
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+//
+// 同一 RedisMutex 实例共享 owner，不能用于同进程内多个 goroutine 之间互斥；每个持有方应使用独立实例。
+type RedisMutex struct {
+	rdb    redis.Cmdable
+	key    string
+	opts   lockOptions
+	logger *log.Helper
+
+	mu       sync.Mutex
+	watchdog *watchdog
+}
+
+func NewRedisMutex(rdb redis.Cmdable, key string, opts ...LockOption) *RedisMutex {
+	o := newLockOptions(opts)
+	return &RedisMutex{
+		rdb:    rdb,
+		key:    key,
+		opts:   o,
+		logger: log.NewHelper(log.With(o.logger, "module", "mutex/redis")),
+	}
+}
+
+// Owner 返回本实例的 owner id
+func (m *RedisMutex) Owner() string {
+	return m.opts.owner
+}
+
+// TryLock 尝试加锁一次，不阻塞；已由本 owner 持有时重入计数 +1
+func (m *RedisMutex) TryLock(ctx context.Context) (bool, error) {
+	const lua = `
+if redis.call('EXISTS', KEYS[1]) == 0 or redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+  redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+return 0
+`
+	n, err := m.rdb.Eval(ctx, lua, []string{m.key}, m.opts.owner, m.opts.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if n != 1 {
+		return false, nil
+	}
+	m.startWatchdog()
+	return true, nil
+}
+
+// Lock 阻塞直到加锁成功或 ctx 结束
+func (m *RedisMutex) Lock(ctx context.Context) error {
+	for {
+		ok, err := m.TryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		sleepJitter(ctx, m.opts.retry)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// Unlock 重入计数 -1，归零时删除锁并停止 watchdog；锁已不属于本 owner 时返回 ErrLockNotHeld
+func (m *RedisMutex) Unlock(ctx context.Context) error {
+	const lua = `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+  return -1
+end
+local n = redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+if n > 0 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return n
+end
+redis.call('DEL', KEYS[1])
+return 0
+`
+	n, err := m.rdb.Eval(ctx, lua, []string{m.key}, m.opts.owner, m.opts.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if n > 0 {
+		return nil
+	}
+	m.stopWatchdog()
+	if n < 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (m *RedisMutex) renew(ctx context.Context) (bool, error) {
+	const lua = `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`
+	n, err := m.rdb.Eval(ctx, lua, []string{m.key}, m.opts.owner, m.opts.ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+func (m *RedisMutex) startWatchdog() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.watchdog != nil {
+		return
+	}
+	w := startWatchdog(m.opts.ttl/3, m.renew, func(w *watchdog) {
+		m.logger.Warnf("lock lost, key=%s owner=%s", m.key, m.opts.owner)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.watchdog == w {
+			m.watchdog = nil
+		}
+	})
+	m.watchdog = w
+}
+
+func (m *RedisMutex) stopWatchdog() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.watchdog != nil {
+		m.watchdog.stop()
+		m.watchdog = nil
+	}
+}
+
+// watchdog 持有期间的后台续期协程
+type watchdog struct {
+	cancel context.CancelFunc
+	done   chan struct{} // 续期协程退出后关闭（停止或确认丢失）
+}
+
+// startWatchdog 每 every 调用一次 renew；确认丢失（renew 返回 false）时回调 onLost 并退出。
+// renew 出错不代表丢失，继续下一轮，直到 ttl 自然过期后由 renew 返回 false。
+func startWatchdog(
+	every time.Duration,
+	renew func(context.Context) (bool, error),
+	onLost func(w *watchdog),
+) *watchdog {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watchdog{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		tk := time.NewTicker(every)
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tk.C:
+				ok, err := renew(ctx)
+				if err != nil {
+					continue
+				}
+				if !ok {
+					onLost(w)
+					return
+				}
+			}
+		}
+	}()
+	return w
+}
+
+func (w *watchdog) stop() {
+	w.cancel()
+}
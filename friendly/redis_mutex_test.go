@@ -0,0 +1,171 @@
+package friendly
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewLockOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		o := newLockOptions(nil)
+		if o.ttl != 30*time.Second || o.retry != 100*time.Millisecond {
+			t.Fatalf("unexpected defaults: %+v", o)
+		}
+		if o.owner == "" || o.logger == nil {
+			t.Fatal("expected random owner and default logger")
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		o := newLockOptions([]LockOption{WithLockTTL(time.Second), WithLockOwner("me"), WithLockRetry(time.Millisecond)})
+		if o.ttl != time.Second || o.owner != "me" || o.retry != time.Millisecond {
+			t.Fatalf("unexpected options: %+v", o)
+		}
+	})
+}
+
+func TestWatchdog(t *testing.T) {
+	t.Run("stop on lost", func(t *testing.T) {
+		var calls atomic.Int32
+		lost := make(chan struct{})
+		w := startWatchdog(5*time.Millisecond, func(context.Context) (bool, error) {
+			if calls.Add(1) < 3 {
+				return true, nil
+			}
+			return false, nil
+		}, func(*watchdog) { close(lost) })
+
+		<-lost
+		<-w.done
+		if calls.Load() != 3 {
+			t.Fatalf("unexpected renew calls: %d", calls.Load())
+		}
+	})
+
+	t.Run("renew error does not stop", func(t *testing.T) {
+		var calls atomic.Int32
+		w := startWatchdog(5*time.Millisecond, func(context.Context) (bool, error) {
+			calls.Add(1)
+			return false, errors.New("network")
+		}, func(*watchdog) { t.Error("unexpected lost") })
+
+		time.Sleep(30 * time.Millisecond)
+		w.stop()
+		<-w.done
+		if calls.Load() < 2 {
+			t.Fatalf("expected watchdog to keep renewing, calls=%d", calls.Load())
+		}
+	})
+}
+
+func TestRedisMutexUnreachable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer CloseQuietly(rdb)
+
+	m := NewRedisMutex(rdb, "lock", WithLockOwner("me"))
+	if m.Owner() != "me" {
+		t.Fatalf("unexpected owner: %s", m.Owner())
+	}
+	if ok, err := m.TryLock(context.Background()); ok || err == nil {
+		t.Fatalf("expected error from unreachable redis, ok=%v err=%v", ok, err)
+	}
+	if m.watchdog != nil {
+		t.Fatal("watchdog should not start when lock failed")
+	}
+
+	s := NewRedisSemaphore(rdb, "sem", 2)
+	if p, ok, err := s.TryAcquire(context.Background()); p != nil || ok || err == nil {
+		t.Fatalf("expected error from unreachable redis, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestRedisMutexExclusive(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	ctx := context.Background()
+	a := NewRedisMutex(rdb, "lock", WithLockOwner("a"), WithLockTTL(time.Minute))
+	b := NewRedisMutex(rdb, "lock", WithLockOwner("b"), WithLockTTL(time.Minute))
+
+	if ok, err := a.TryLock(ctx); err != nil || !ok {
+		t.Fatalf("a lock ok=%v err=%v", ok, err)
+	}
+	if ok, err := b.TryLock(ctx); err != nil || ok {
+		t.Fatalf("b should not lock while a holds it, ok=%v err=%v", ok, err)
+	}
+	if err := b.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("unlock by non-owner err=%v", err)
+	}
+	if count, err := rdb.HGet(ctx, "lock", "a").Result(); err != nil || count != "1" {
+		t.Fatalf("non-owner unlock should not touch the lock, count=%q err=%v", count, err)
+	}
+
+	if err := a.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := b.TryLock(ctx); err != nil || !ok {
+		t.Fatalf("b lock after release ok=%v err=%v", ok, err)
+	}
+	_ = b.Unlock(ctx)
+}
+
+func TestRedisMutexReentrant(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	ctx := context.Background()
+	m := NewRedisMutex(rdb, "lock", WithLockOwner("me"), WithLockTTL(time.Minute))
+
+	for i := 0; i < 2; i++ {
+		if ok, err := m.TryLock(ctx); err != nil || !ok {
+			t.Fatalf("lock %d ok=%v err=%v", i, ok, err)
+		}
+	}
+	if n, err := rdb.HGet(ctx, "lock", "me").Int64(); err != nil || n != 2 {
+		t.Fatalf("reentrant count=%d err=%v", n, err)
+	}
+
+	if err := m.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := rdb.Exists(ctx, "lock").Result(); err != nil || n != 1 {
+		t.Fatalf("lock should be held after first unlock, exists=%d err=%v", n, err)
+	}
+	if err := m.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := rdb.Exists(ctx, "lock").Result(); err != nil || n != 0 {
+		t.Fatalf("lock should be deleted after second unlock, exists=%d err=%v", n, err)
+	}
+	if m.watchdog != nil {
+		t.Fatal("watchdog should stop after final unlock")
+	}
+	if err := m.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("extra unlock err=%v", err)
+	}
+}
+
+func TestRedisMutexWatchdogExtendsTTL(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	ctx := context.Background()
+	m := NewRedisMutex(rdb, "lock", WithLockOwner("me"), WithLockTTL(300*time.Millisecond))
+
+	if ok, err := m.TryLock(ctx); err != nil || !ok {
+		t.Fatalf("lock ok=%v err=%v", ok, err)
+	}
+	defer func() { _ = m.Unlock(ctx) }()
+
+	// miniredis 的 TTL 只随 FastForward 递减：快进到接近过期，等待 watchdog（每 ttl/3）续期
+	mr.FastForward(250 * time.Millisecond)
+	if ttl := mr.TTL("lock"); ttl > 100*time.Millisecond {
+		t.Fatalf("ttl before renew=%s", ttl)
+	}
+	deadline := time.Now().Add(time.Second)
+	for mr.TTL("lock") <= 100*time.Millisecond {
+		if time.Now().After(deadline) {
+			t.Fatalf("watchdog did not extend ttl, ttl=%s", mr.TTL("lock"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,320 @@
+package friendly
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPartitionLeader 将 N 个分区分摊到多个实例，每个分区是一把独立的租约（key:0 … key:N-1）：
+// - 抢占 / 续期 / 释放沿用 RedisLeaseStore（SET NX PX + epoch，compare-and-renew / compare-and-del）
+// - 实例通过心跳 hash（key:members）登记存活，超过 ttl 未心跳视为离开
+// - 每轮按存活实例数计算公平份额：多于份额时主动释放，少于份额时抢占空闲分区
+// - 分区到手时 onAssigned 收到该分区的工作 ctx（可用 EpochFromContext 取 fencing token），失去时 ctx 被 cancel 并回调 onRevoked
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+type RedisPartitionLeader struct {
+	rdb        redis.Cmdable
+	store      *RedisLeaseStore
+	key        string
+	membersKey string
+	partitions int
+	id         string
+	ttl        time.Duration
+	renewEvery time.Duration
+	logger     *log.Helper
+	onAssigned func(ctx context.Context, partition int)
+	onRevoked  func(ctx context.Context, partition int)
+
+	mu    sync.RWMutex
+	owned map[int]*leaderTerm
+
+	running atomic.Bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewRedisPartitionLeader(
+	rdb redis.Cmdable,
+	key string,
+	partitions int,
+	ttl, renewEvery time.Duration,
+	logger log.Logger,
+	onAssigned func(ctx context.Context, partition int),
+	onRevoked func(ctx context.Context, partition int),
+) *RedisPartitionLeader {
+	if renewEvery <= 0 || (ttl > 0 && renewEvery >= ttl) {
+		renewEvery = ttl / 3
+	}
+	return &RedisPartitionLeader{
+		rdb:        rdb,
+		store:      NewRedisLeaseStore(rdb),
+		key:        key,
+		membersKey: key + ":members",
+		partitions: partitions,
+		id:         randID(),
+		ttl:        ttl,
+		renewEvery: renewEvery,
+		logger:     log.NewHelper(log.With(logger, "module", "leader/partition")),
+		onAssigned: onAssigned,
+		onRevoked:  onRevoked,
+		owned:      make(map[int]*leaderTerm),
+	}
+}
+
+// ID 返回本实例 id
+func (l *RedisPartitionLeader) ID() string {
+	return l.id
+}
+
+// PartitionKey 返回分区 p 的租约 key
+func (l *RedisPartitionLeader) PartitionKey(p int) string {
+	return fmt.Sprintf("%s:%d", l.key, p)
+}
+
+// Owned 返回本实例当前持有的分区（升序）
+func (l *RedisPartitionLeader) Owned() []int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]int, 0, len(l.owned))
+	for p := range l.owned {
+		out = append(out, p)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Owner 从 Redis 读取分区 p 当前持有者的实例 id；无人持有时返回空串
+func (l *RedisPartitionLeader) Owner(ctx context.Context, p int) (string, error) {
+	return l.store.Holder(ctx, l.PartitionKey(p))
+}
+
+// Start 启动分区循环：心跳 → 续期 → 按份额释放或抢占
+func (l *RedisPartitionLeader) Start(ctx context.Context) error {
+	if !l.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	l.cancel = cancel
+
+	l.wg.Add(1)
+	go l.loop(runCtx)
+
+	l.logger.Infof("partition loop started, key=%s partitions=%d ttl=%s renew=%s id=%s",
+		l.key, l.partitions, l.ttl, l.renewEvery, l.id)
+	return nil
+}
+
+// Stop 停止循环，释放全部分区并注销成员
+func (l *RedisPartitionLeader) Stop(ctx context.Context) error {
+	if !l.running.CompareAndSwap(true, false) {
+		return nil
+	}
+	if l.cancel != nil {
+		l.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *RedisPartitionLeader) loop(ctx context.Context) {
+	defer l.wg.Done()
+
+	tk := time.NewTicker(l.renewEvery)
+	defer tk.Stop()
+
+	for {
+		l.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			l.shutdown()
+			return
+		case <-tk.C:
+		}
+	}
+}
+
+func (l *RedisPartitionLeader) tick(ctx context.Context) {
+	members, err := l.heartbeat(ctx)
+	if err != nil {
+		// 心跳失败时无法判断份额，只续期已持有的分区
+		l.logger.Warnf("heartbeat error: %+v", err)
+	}
+	l.renewOwned(ctx)
+	if err != nil || ctx.Err() != nil {
+		return
+	}
+
+	share := fairShare(l.partitions, members, l.id)
+	owned := l.Owned()
+	if len(owned) > share {
+		// 释放编号最大的多余分区，留给新加入的实例
+		for _, p := range owned[share:] {
+			l.revoke(p, true, "rebalance")
+		}
+		return
+	}
+
+	for _, p := range partitionOrder(l.partitions, members, l.id) {
+		if len(l.Owned()) >= share || ctx.Err() != nil {
+			return
+		}
+		if l.isOwned(p) {
+			continue
+		}
+		epoch, err := l.store.TryAcquire(ctx, l.PartitionKey(p), l.id, l.ttl)
+		if err != nil {
+			l.logger.Warnf("acquire partition %d error: %+v", p, err)
+			return
+		}
+		if epoch > 0 {
+			l.assign(ctx, p, epoch)
+		}
+	}
+}
+
+// heartbeat 登记本实例并清理过期成员，返回存活成员 id（升序）
+func (l *RedisPartitionLeader) heartbeat(ctx context.Context) ([]string, error) {
+	const lua = `
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+local all = redis.call('HGETALL', KEYS[1])
+local live = {}
+for i = 1, #all, 2 do
+  if tonumber(all[i + 1]) >= tonumber(ARGV[3]) then
+    table.insert(live, all[i])
+  else
+    redis.call('HDEL', KEYS[1], all[i])
+  end
+end
+return live
+`
+	now := time.Now()
+	members, err := l.rdb.Eval(ctx, lua, []string{l.membersKey},
+		l.id, now.UnixMilli(), now.Add(-l.ttl).UnixMilli(), l.ttl.Milliseconds(),
+	).StringSlice()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func (l *RedisPartitionLeader) renewOwned(ctx context.Context) {
+	for _, p := range l.Owned() {
+		ok, err := l.store.Renew(ctx, l.PartitionKey(p), l.id, l.ttl)
+		if err != nil {
+			// 出错不等于丢失，下一轮再尝试
+			l.logger.Warnf("renew partition %d error: %+v", p, err)
+			continue
+		}
+		if !ok {
+			l.revoke(p, false, "lock no longer owned")
+		}
+	}
+}
+
+func (l *RedisPartitionLeader) isOwned(p int) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.owned[p]
+	return ok
+}
+
+func (l *RedisPartitionLeader) assign(parent context.Context, p int, epoch int64) {
+	workCtx, cancel := context.WithCancel(context.WithValue(parent, epochCtxKey{}, epoch))
+	term := &leaderTerm{epoch: epoch, cancel: cancel, done: make(chan struct{})}
+
+	l.mu.Lock()
+	l.owned[p] = term
+	l.mu.Unlock()
+
+	l.logger.Infof("partition assigned, key=%s partition=%d epoch=%d id=%s", l.key, p, epoch, l.id)
+	go func() {
+		defer close(term.done)
+		if l.onAssigned != nil {
+			l.onAssigned(workCtx, p)
+		}
+	}()
+}
+
+// revoke 先 cancel 分区工作 ctx 再回调 onRevoked；release=true 时释放租约（仅当仍由本实例持有）
+func (l *RedisPartitionLeader) revoke(p int, release bool, reason string) {
+	l.mu.Lock()
+	term, ok := l.owned[p]
+	delete(l.owned, p)
+	l.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	term.cancel()
+	if l.onRevoked != nil {
+		go l.onRevoked(context.Background(), p)
+	}
+	if release {
+		if err := l.store.Release(context.Background(), l.PartitionKey(p), l.id, ReleaseOptions{}); err != nil {
+			l.logger.Warnf("release partition %d error: %+v", p, err)
+		}
+	}
+	l.logger.Infof("partition revoked, key=%s partition=%d epoch=%d reason=%s", l.key, p, term.epoch, reason)
+}
+
+func (l *RedisPartitionLeader) shutdown() {
+	for _, p := range l.Owned() {
+		l.revoke(p, true, "partition loop stopped")
+	}
+	if err := l.rdb.HDel(context.Background(), l.membersKey, l.id).Err(); err != nil {
+		l.logger.Warnf("deregister member error: %+v", err)
+	}
+}
+
+// fairShare 计算 id 应持有的分区数：n 个分区按成员升序均分，余数分给靠前的成员。
+// id 尚未出现在 members 中（心跳尚未可见）时按已加入处理。
+func fairShare(n int, members []string, id string) int {
+	if !slices.Contains(members, id) {
+		members = append(slices.Clone(members), id)
+		sort.Strings(members)
+	}
+	m := len(members)
+	idx := slices.Index(members, id)
+	share := n / m
+	if idx < n%m {
+		share++
+	}
+	return share
+}
+
+// partitionOrder 返回抢占顺序：从 id 在成员中的位置对应的偏移开始轮转，减少实例间的争抢
+func partitionOrder(n int, members []string, id string) []int {
+	if n <= 0 {
+		return nil
+	}
+	offset := 0
+	if idx := slices.Index(members, id); idx > 0 {
+		offset = idx * n / len(members)
+	}
+	out := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, (offset+i)%n)
+	}
+	return out
+}
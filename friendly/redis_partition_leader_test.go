@@ -0,0 +1,80 @@
+package friendly
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestFairShare(t *testing.T) {
+	members := []string{"a", "b", "c"}
+	cases := []struct {
+		n    int
+		id   string
+		want int
+	}{
+		{n: 10, id: "a", want: 4},
+		{n: 10, id: "b", want: 3},
+		{n: 10, id: "c", want: 3},
+		{n: 2, id: "c", want: 0},
+		{n: 10, id: "0", want: 3}, // 尚未登记的成员按已加入计算：4 个成员，排在最前
+	}
+	for _, c := range cases {
+		if got := fairShare(c.n, members, c.id); got != c.want {
+			t.Fatalf("fairShare(%d, %v, %q)=%d want=%d", c.n, members, c.id, got, c.want)
+		}
+	}
+
+	total := 0
+	for _, id := range members {
+		total += fairShare(7, members, id)
+	}
+	if total != 7 {
+		t.Fatalf("shares should cover all partitions, got %d", total)
+	}
+}
+
+func TestPartitionOrder(t *testing.T) {
+	got := partitionOrder(6, []string{"a", "b", "c"}, "b")
+	if !slices.Equal(got, []int{2, 3, 4, 5, 0, 1}) {
+		t.Fatalf("unexpected order: %v", got)
+	}
+	if got := partitionOrder(0, nil, "a"); got != nil {
+		t.Fatalf("expected nil order for zero partitions, got %v", got)
+	}
+}
+
+func TestRedisPartitionLeaderAssignRevoke(t *testing.T) {
+	assigned := make(chan context.Context, 1)
+	revoked := make(chan int, 1)
+	l := NewRedisPartitionLeader(nil, "jobs", 4, time.Second, 0, log.DefaultLogger,
+		func(ctx context.Context, p int) { assigned <- ctx },
+		func(_ context.Context, p int) { revoked <- p },
+	)
+	if l.PartitionKey(2) != "jobs:2" {
+		t.Fatalf("unexpected partition key: %s", l.PartitionKey(2))
+	}
+
+	l.assign(context.Background(), 2, 5)
+	ctx := <-assigned
+	if epoch, _ := EpochFromContext(ctx); epoch != 5 {
+		t.Fatalf("unexpected partition epoch: %d", epoch)
+	}
+	if !slices.Equal(l.Owned(), []int{2}) {
+		t.Fatalf("unexpected owned: %v", l.Owned())
+	}
+
+	l.revoke(2, false, "test")
+	if ctx.Err() == nil {
+		t.Fatal("expected partition ctx canceled")
+	}
+	if p := <-revoked; p != 2 {
+		t.Fatalf("unexpected revoked partition: %d", p)
+	}
+	if len(l.Owned()) != 0 {
+		t.Fatalf("expected no owned partitions, got %v", l.Owned())
+	}
+}
@@ -0,0 +1,142 @@
+package friendly
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisSemaphore 基于 Redis 有序集合的分布式计数信号量：
+// - member 为许可 id，score 为过期时间（毫秒时间戳），获取前先清理已过期的许可
+// - 同时持有的许可数不超过 limit；持有期间 watchdog 自动刷新过期时间
+// - 过期时间取自客户端时钟，实例间时钟偏差会影响过期判定的精度
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+type RedisSemaphore struct {
+	rdb    redis.Cmdable
+	key    string
+	limit  int64
+	opts   lockOptions
+	logger *log.Helper
+}
+
+func NewRedisSemaphore(rdb redis.Cmdable, key string, limit int64, opts ...LockOption) *RedisSemaphore {
+	o := newLockOptions(opts)
+	return &RedisSemaphore{
+		rdb:    rdb,
+		key:    key,
+		limit:  limit,
+		opts:   o,
+		logger: log.NewHelper(log.With(o.logger, "module", "semaphore/redis")),
+	}
+}
+
+// TryAcquire 尝试获取一个许可，不阻塞；许可已满时返回 (nil, false, nil)
+func (s *RedisSemaphore) TryAcquire(ctx context.Context) (*SemaphorePermit, bool, error) {
+	const lua = `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[4]) then
+  redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+  redis.call('PEXPIRE', KEYS[1], ARGV[5])
+  return 1
+end
+return 0
+`
+	id := randID()
+	now := time.Now()
+	n, err := s.rdb.Eval(ctx, lua, []string{s.key},
+		now.UnixMilli(), now.Add(s.opts.ttl).UnixMilli(), id, s.limit, s.opts.ttl.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	if n != 1 {
+		return nil, false, nil
+	}
+
+	p := &SemaphorePermit{sem: s, id: id}
+	p.watchdog = startWatchdog(s.opts.ttl/3, p.refresh, func(*watchdog) {
+		s.logger.Warnf("permit lost, key=%s id=%s", s.key, id)
+	})
+	return p, true, nil
+}
+
+// Acquire 阻塞直到获取许可或 ctx 结束
+func (s *RedisSemaphore) Acquire(ctx context.Context) (*SemaphorePermit, error) {
+	for {
+		p, ok, err := s.TryAcquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return p, nil
+		}
+		sleepJitter(ctx, s.opts.retry)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Count 返回当前未过期的许可数
+func (s *RedisSemaphore) Count(ctx context.Context) (int64, error) {
+	n, err := s.rdb.ZCount(ctx, s.key, "("+strconv.FormatInt(time.Now().UnixMilli(), 10), "+inf").Result()
+	return n, errors.WithStack(err)
+}
+
+// SemaphorePermit 已获取的信号量许可
+type SemaphorePermit struct {
+	sem      *RedisSemaphore
+	id       string
+	watchdog *watchdog
+	once     sync.Once
+}
+
+// ID 返回许可 id（有序集合中的 member）
+func (p *SemaphorePermit) ID() string {
+	return p.id
+}
+
+// Lost 许可被确认丢失（过期后被清理）或已释放时关闭
+func (p *SemaphorePermit) Lost() <-chan struct{} {
+	return p.watchdog.done
+}
+
+// Release 归还许可；许可已过期被清理时返回 ErrLockNotHeld
+func (p *SemaphorePermit) Release(ctx context.Context) error {
+	var err error
+	p.once.Do(func() {
+		p.watchdog.stop()
+		var n int64
+		n, err = p.sem.rdb.ZRem(ctx, p.sem.key, p.id).Result()
+		if err != nil {
+			err = errors.WithStack(err)
+			return
+		}
+		if n == 0 {
+			err = ErrLockNotHeld
+		}
+	})
+	return err
+}
+
+func (p *SemaphorePermit) refresh(ctx context.Context) (bool, error) {
+	// 仅当许可仍存在时刷新过期时间（ZADD XX 不会重新加入已被清理的许可）
+	const lua = `
+if redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+  redis.call('ZADD', KEYS[1], 'XX', ARGV[1], ARGV[2])
+  redis.call('PEXPIRE', KEYS[1], ARGV[3])
+  return 1
+end
+return 0
+`
+	ttl := p.sem.opts.ttl
+	n, err := p.sem.rdb.Eval(ctx, lua, []string{p.sem.key},
+		time.Now().Add(ttl).UnixMilli(), p.id, ttl.Milliseconds(),
+	).Int64()
+	return n == 1, err
+}
@@ -0,0 +1,105 @@
+package friendly
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisSemaphoreLimit(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	ctx := context.Background()
+	s := NewRedisSemaphore(rdb, "sem", 2, WithLockTTL(time.Minute))
+
+	p1, ok, err := s.TryAcquire(ctx)
+	if err != nil || !ok {
+		t.Fatalf("acquire 1 ok=%v err=%v", ok, err)
+	}
+	p2, ok, err := s.TryAcquire(ctx)
+	if err != nil || !ok {
+		t.Fatalf("acquire 2 ok=%v err=%v", ok, err)
+	}
+	defer func() { _ = p2.Release(ctx) }()
+	if p, ok, err := s.TryAcquire(ctx); err != nil || ok || p != nil {
+		t.Fatalf("acquire over limit p=%v ok=%v err=%v", p, ok, err)
+	}
+	if n, err := s.Count(ctx); err != nil || n != 2 {
+		t.Fatalf("count=%d err=%v", n, err)
+	}
+
+	if err := p1.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	p3, ok, err := s.TryAcquire(ctx)
+	if err != nil || !ok {
+		t.Fatalf("acquire after release ok=%v err=%v", ok, err)
+	}
+	defer func() { _ = p3.Release(ctx) }()
+
+	// 已过期的许可在获取前被清理，不占用名额
+	if err := rdb.ZAdd(ctx, "sem", redis.Z{Score: float64(time.Now().Add(-time.Second).UnixMilli()), Member: "stale"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p3.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	p4, ok, err := s.TryAcquire(ctx)
+	if err != nil || !ok {
+		t.Fatalf("expired permit should be purged ok=%v err=%v", ok, err)
+	}
+	defer func() { _ = p4.Release(ctx) }()
+	if rdb.ZScore(ctx, "sem", "stale").Err() != redis.Nil {
+		t.Fatal("expired permit should be removed")
+	}
+}
+
+func TestRedisSemaphorePermitLost(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	ctx := context.Background()
+	s := NewRedisSemaphore(rdb, "sem", 1, WithLockTTL(time.Minute))
+
+	p, ok, err := s.TryAcquire(ctx)
+	if err != nil || !ok {
+		t.Fatalf("acquire ok=%v err=%v", ok, err)
+	}
+	if ok, err := p.refresh(ctx); err != nil || !ok {
+		t.Fatalf("refresh held permit ok=%v err=%v", ok, err)
+	}
+
+	// 模拟许可过期后被其他实例清理
+	if err := rdb.ZRem(ctx, "sem", p.ID()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := p.refresh(ctx); err != nil || ok {
+		t.Fatalf("refresh purged permit ok=%v err=%v", ok, err)
+	}
+	if rdb.ZScore(ctx, "sem", p.ID()).Err() != redis.Nil {
+		t.Fatal("refresh should not re-add a purged permit")
+	}
+	if err := p.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("release lost permit err=%v", err)
+	}
+	if err := p.Release(ctx); err != nil {
+		t.Fatalf("second release err=%v", err)
+	}
+}
+
+func TestRedisSemaphoreAcquireContext(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	s := NewRedisSemaphore(rdb, "sem", 1, WithLockTTL(time.Minute), WithLockRetry(5*time.Millisecond))
+
+	p, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Release(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire when full err=%v", err)
+	}
+}
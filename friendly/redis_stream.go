@@ -0,0 +1,419 @@
+package friendly
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamHandler 处理单条消息；返回 nil 时 ACK，返回错误时保留在 PEL 中等待重新投递
+type StreamHandler func(ctx context.Context, msg redis.XMessage) error
+
+type streamOptions struct {
+	consumer      string
+	batch         int64
+	concurrency   int
+	block         time.Duration
+	startID       string
+	claimIdle     time.Duration
+	claimEvery    time.Duration
+	maxDeliveries int64
+	deadLetter    string
+}
+
+// StreamOption StreamConsumer 的可选参数
+type StreamOption func(o *streamOptions)
+
+// WithStreamConsumerName 消费者名称，默认随机 id；固定名称可在重启后继续处理自己的 PEL
+func WithStreamConsumerName(name string) StreamOption {
+	return func(o *streamOptions) { o.consumer = name }
+}
+
+// WithStreamBatch 每次 XREADGROUP / XAUTOCLAIM 的最大条数，默认 10
+func WithStreamBatch(n int64) StreamOption {
+	return func(o *streamOptions) { o.batch = n }
+}
+
+// WithStreamConcurrency 并发处理的 handler 数，默认 4
+func WithStreamConcurrency(n int) StreamOption {
+	return func(o *streamOptions) { o.concurrency = n }
+}
+
+// WithStreamBlock XREADGROUP 的阻塞时长，默认 2s；Stop 最多需要等待一个 block 周期
+func WithStreamBlock(d time.Duration) StreamOption {
+	return func(o *streamOptions) { o.block = d }
+}
+
+// WithStreamStartID 消费组不存在时的创建位置，默认 $（只消费新消息），0 表示从头消费
+func WithStreamStartID(id string) StreamOption {
+	return func(o *streamOptions) { o.startID = id }
+}
+
+// WithStreamClaim 每 every 扫描一次空闲超过 idle 的待确认消息并接管，默认 idle=1m every=30s；idle<=0 时关闭接管
+func WithStreamClaim(idle, every time.Duration) StreamOption {
+	return func(o *streamOptions) { o.claimIdle, o.claimEvery = idle, every }
+}
+
+// WithStreamDeadLetter 投递次数达到 maxDeliveries 的消息转入死信 stream 并 ACK，
+// 默认 maxDeliveries=5、stream 为 <stream>:dead；maxDeliveries<=0 时关闭死信
+func WithStreamDeadLetter(stream string, maxDeliveries int64) StreamOption {
+	return func(o *streamOptions) { o.deadLetter, o.maxDeliveries = stream, maxDeliveries }
+}
+
+// StreamConsumer 基于 Redis Streams 消费组的后台消费者：
+// - XREADGROUP 批量拉取新消息，由固定数量的 worker 并发处理，成功后 XACK
+// - 定期 XAUTOCLAIM 接管其他（已宕机）消费者长时间未确认的消息
+// - 投递次数超过上限的消息写入死信 stream 后 ACK，避免毒消息无限重试
+// - Stop 停止拉取并等待已拉取的消息处理完毕；ctx 到期时取消 handler 的 ctx
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+type StreamConsumer struct {
+	rdb     redis.Cmdable
+	stream  string
+	group   string
+	handler StreamHandler
+	opts    streamOptions
+	logger  *log.Helper
+
+	jobs    chan redis.XMessage
+	running atomic.Bool
+	cancel  context.CancelFunc // 停止拉取
+	abort   context.CancelFunc // 取消 handler
+	readers sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+func NewStreamConsumer(
+	rdb redis.Cmdable,
+	stream, group string,
+	handler StreamHandler,
+	logger log.Logger,
+	opts ...StreamOption,
+) *StreamConsumer {
+	o := streamOptions{
+		consumer:      randID(),
+		batch:         10,
+		concurrency:   4,
+		block:         2 * time.Second,
+		startID:       "$",
+		claimIdle:     time.Minute,
+		claimEvery:    30 * time.Second,
+		maxDeliveries: 5,
+		deadLetter:    stream + ":dead",
+	}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if o.batch <= 0 {
+		o.batch = 10
+	}
+	if o.concurrency <= 0 {
+		o.concurrency = 1
+	}
+	if o.claimEvery <= 0 {
+		o.claimEvery = o.claimIdle / 2
+	}
+	return &StreamConsumer{
+		rdb:     rdb,
+		stream:  stream,
+		group:   group,
+		handler: handler,
+		opts:    o,
+		logger:  log.NewHelper(log.With(logger, "module", "stream/consumer")),
+	}
+}
+
+// Consumer 返回消费者名称
+func (c *StreamConsumer) Consumer() string {
+	return c.opts.consumer
+}
+
+// Start 启动拉取循环、接管循环与 worker；消费组不存在时自动创建（MKSTREAM）
+func (c *StreamConsumer) Start(ctx context.Context) error {
+	if !c.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	handlerCtx, abort := context.WithCancel(context.WithoutCancel(ctx))
+	runCtx, cancel := context.WithCancel(ctx)
+	c.cancel, c.abort = cancel, abort
+	c.jobs = make(chan redis.XMessage)
+
+	for i := 0; i < c.opts.concurrency; i++ {
+		c.workers.Add(1)
+		go c.work(handlerCtx)
+	}
+
+	c.readers.Add(1)
+	go c.readLoop(runCtx, handlerCtx)
+	if c.opts.claimIdle > 0 {
+		c.readers.Add(1)
+		go c.claimLoop(runCtx, handlerCtx)
+	}
+
+	c.logger.Infof("stream consumer started, stream=%s group=%s consumer=%s concurrency=%d",
+		c.stream, c.group, c.opts.consumer, c.opts.concurrency)
+	return nil
+}
+
+// Stop 停止拉取并等待在途消息处理完毕；ctx 到期时取消 handler 并返回 ctx.Err()
+func (c *StreamConsumer) Stop(ctx context.Context) error {
+	if !c.running.CompareAndSwap(true, false) {
+		return nil
+	}
+	c.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.readers.Wait()
+		close(c.jobs)
+		c.workers.Wait()
+	}()
+
+	select {
+	case <-done:
+		c.abort()
+		return nil
+	case <-ctx.Done():
+		c.abort()
+		return ctx.Err()
+	}
+}
+
+func (c *StreamConsumer) readLoop(ctx, handlerCtx context.Context) {
+	defer c.readers.Done()
+
+	backoff := 300 * time.Millisecond
+	groupReady := false
+	for ctx.Err() == nil {
+		if !groupReady {
+			if err := c.ensureGroup(ctx); err != nil {
+				c.logger.Warnf("create group failed, stream=%s group=%s err=%v", c.stream, c.group, err)
+				sleepJitter(ctx, backoff)
+				continue
+			}
+			groupReady = true
+		}
+
+		res, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.opts.consumer,
+			Streams:  []string{c.stream, ">"},
+			Count:    c.opts.batch,
+			Block:    c.opts.block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				groupReady = false // stream 被删除后重建
+			}
+			c.logger.Warnf("xreadgroup failed, stream=%s err=%v", c.stream, err)
+			sleepJitter(ctx, backoff)
+			continue
+		}
+		for _, s := range res {
+			c.dispatch(handlerCtx, s.Messages)
+		}
+	}
+}
+
+// ensureGroup 创建消费组，已存在时忽略
+func (c *StreamConsumer) ensureGroup(ctx context.Context) error {
+	err := c.rdb.XGroupCreateMkStream(ctx, c.stream, c.group, c.opts.startID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (c *StreamConsumer) claimLoop(ctx, handlerCtx context.Context) {
+	defer c.readers.Done()
+
+	tk := time.NewTicker(c.opts.claimEvery)
+	defer tk.Stop()
+
+	cursor := "0-0"
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tk.C:
+		}
+
+		if c.opts.maxDeliveries > 0 {
+			if err := c.deadLetterExhausted(ctx); err != nil && ctx.Err() == nil {
+				c.logger.Warnf("dead letter scan failed, stream=%s err=%v", c.stream, err)
+			}
+		}
+
+		msgs, next, err := c.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.stream,
+			Group:    c.group,
+			Consumer: c.opts.consumer,
+			MinIdle:  c.opts.claimIdle,
+			Start:    cursor,
+			Count:    c.opts.batch,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Warnf("xautoclaim failed, stream=%s err=%v", c.stream, err)
+			}
+			continue
+		}
+		cursor = next
+		if len(msgs) > 0 {
+			c.logger.Infof("claimed %d pending messages, stream=%s", len(msgs), c.stream)
+			c.dispatch(handlerCtx, msgs)
+		}
+	}
+}
+
+// deadLetterExhausted 将投递次数达到上限的待确认消息转入死信 stream；
+// 按 ID 顺序分页扫描整个待确认列表，避免靠前的仍在重试的消息挡住其后已耗尽的消息；
+// 先以 XCLAIM（带 min-idle）取得所有权，多个实例并发扫描时只有一个能转移成功
+func (c *StreamConsumer) deadLetterExhausted(ctx context.Context) error {
+	start := "-"
+	for {
+		pending, err := c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: c.stream,
+			Group:  c.group,
+			Idle:   c.opts.claimIdle,
+			Start:  start,
+			End:    "+",
+			Count:  c.opts.batch,
+		}).Result()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		deliveries := make(map[string]int64)
+		var ids []string
+		for _, p := range pending {
+			if p.RetryCount >= c.opts.maxDeliveries {
+				ids = append(ids, p.ID)
+				deliveries[p.ID] = p.RetryCount
+			}
+		}
+		if len(ids) > 0 {
+			if err := c.deadLetter(ctx, ids, deliveries); err != nil {
+				return err
+			}
+		}
+		if int64(len(pending)) < c.opts.batch {
+			return nil
+		}
+		if start, err = nextStreamID(pending[len(pending)-1].ID); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *StreamConsumer) deadLetter(ctx context.Context, ids []string, deliveries map[string]int64) error {
+	msgs, err := c.rdb.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: c.opts.consumer,
+		MinIdle:  c.opts.claimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, m := range msgs {
+		if err := c.rdb.XAdd(ctx, &redis.XAddArgs{
+			Stream: c.opts.deadLetter,
+			Values: deadLetterValues(c.stream, c.group, m, deliveries[m.ID]),
+		}).Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := c.rdb.XAck(ctx, c.stream, c.group, m.ID).Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		c.logger.Warnf("message moved to dead letter, stream=%s id=%s deliveries=%d dead=%s",
+			c.stream, m.ID, deliveries[m.ID], c.opts.deadLetter)
+	}
+	return nil
+}
+
+// nextStreamID 返回紧随 id 之后的 stream ID，用作分页的起点（XPENDING 的 "(" 开区间需要 Redis 6.2+）
+func nextStreamID(id string) (string, error) {
+	msStr, seqStr, ok := strings.Cut(id, "-")
+	if !ok {
+		return "", errors.Errorf("invalid stream id %q", id)
+	}
+	ms, err := strconv.ParseUint(msStr, 10, 64)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid stream id %q", id)
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid stream id %q", id)
+	}
+	if seq == math.MaxUint64 {
+		return strconv.FormatUint(ms+1, 10) + "-0", nil
+	}
+	return msStr + "-" + strconv.FormatUint(seq+1, 10), nil
+}
+
+// deadLetterValues 原字段 + 来源信息（dlq_ 前缀）
+func deadLetterValues(stream, group string, msg redis.XMessage, deliveries int64) map[string]interface{} {
+	out := make(map[string]interface{}, len(msg.Values)+4)
+	for k, v := range msg.Values {
+		out[k] = v
+	}
+	out["dlq_stream"] = stream
+	out["dlq_group"] = group
+	out["dlq_id"] = msg.ID
+	out["dlq_deliveries"] = strconv.FormatInt(deliveries, 10)
+	return out
+}
+
+// dispatch 将一批消息交给 worker；只在 handler 被取消（Stop 超时）时丢弃，未处理的消息留在 PEL 中等待接管
+func (c *StreamConsumer) dispatch(ctx context.Context, msgs []redis.XMessage) {
+	for _, m := range msgs {
+		select {
+		case c.jobs <- m:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *StreamConsumer) work(ctx context.Context) {
+	defer c.workers.Done()
+	for m := range c.jobs {
+		if ctx.Err() != nil {
+			continue
+		}
+		// XAUTOCLAIM 可能返回已被 XDEL 的消息（Values 为空），直接确认
+		if len(m.Values) > 0 {
+			if err := c.safeHandle(ctx, m); err != nil {
+				c.logger.Errorf("handle message failed, stream=%s id=%s err=%v", c.stream, m.ID, err)
+				continue
+			}
+		}
+		if err := c.rdb.XAck(context.WithoutCancel(ctx), c.stream, c.group, m.ID).Err(); err != nil {
+			c.logger.Warnf("xack failed, stream=%s id=%s err=%v", c.stream, m.ID, err)
+		}
+	}
+}
+
+// safeHandle 调用 handler，panic 视为处理失败
+func (c *StreamConsumer) safeHandle(ctx context.Context, m redis.XMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.WithStack(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	return c.handler(ctx, m)
+}
@@ -0,0 +1,144 @@
+package friendly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDeadLetterValues(t *testing.T) {
+	msg := redis.XMessage{ID: "1-0", Values: map[string]interface{}{"order": "42"}}
+	got := deadLetterValues("orders", "billing", msg, 5)
+
+	want := map[string]string{
+		"order":          "42",
+		"dlq_stream":     "orders",
+		"dlq_group":      "billing",
+		"dlq_id":         "1-0",
+		"dlq_deliveries": "5",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("values=%v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s=%v want=%s", k, got[k], v)
+		}
+	}
+	if len(msg.Values) != 1 {
+		t.Fatal("original message should not be modified")
+	}
+}
+
+func TestStreamConsumerSafeHandle(t *testing.T) {
+	c := NewStreamConsumer(nil, "s", "g", func(context.Context, redis.XMessage) error {
+		panic("boom")
+	}, log.DefaultLogger)
+	if err := c.safeHandle(context.Background(), redis.XMessage{ID: "1-0"}); err == nil {
+		t.Fatal("panic should be converted to error")
+	}
+}
+
+func TestStreamConsumerDefaults(t *testing.T) {
+	c := NewStreamConsumer(nil, "orders", "g", nil, log.DefaultLogger,
+		WithStreamBatch(0), WithStreamConcurrency(-1), WithStreamClaim(time.Minute, 0))
+	if c.opts.batch != 10 || c.opts.concurrency != 1 || c.opts.claimEvery != 30*time.Second {
+		t.Fatalf("opts=%+v", c.opts)
+	}
+	if c.opts.deadLetter != "orders:dead" || c.opts.maxDeliveries != 5 || c.Consumer() == "" {
+		t.Fatalf("opts=%+v", c.opts)
+	}
+}
+
+func TestStreamConsumerStartStopUnavailable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	c := NewStreamConsumer(rdb, "s", "g", func(context.Context, redis.XMessage) error { return nil }, log.DefaultLogger)
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := c.Stop(ctx); err != nil {
+		t.Fatalf("second stop should be no-op: %v", err)
+	}
+}
+
+func TestNextStreamID(t *testing.T) {
+	cases := map[string]string{
+		"1-0":                    "1-1",
+		"1700000000000-41":       "1700000000000-42",
+		"5-18446744073709551615": "6-0",
+	}
+	for in, want := range cases {
+		if got, err := nextStreamID(in); err != nil || got != want {
+			t.Fatalf("nextStreamID(%q)=%q err=%v want=%q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "1", "a-1", "1-b"} {
+		if _, err := nextStreamID(bad); err == nil {
+			t.Fatalf("nextStreamID(%q) should fail", bad)
+		}
+	}
+}
+
+func TestStreamConsumerDeadLetterPages(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	ctx := context.Background()
+	if err := rdb.XGroupCreateMkStream(ctx, "orders", "billing", "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id, err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: "orders", Values: map[string]interface{}{"n": i}}).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "billing", Consumer: "c1", Streams: []string{"orders", ">"}, Count: 5,
+	}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	// 前 4 条仍在重试，只有排在最后的一条耗尽了投递次数
+	for i, id := range ids {
+		retries := 1
+		if i == len(ids)-1 {
+			retries = 3
+		}
+		if err := rdb.Do(ctx, "XCLAIM", "orders", "billing", "c1", 0, id,
+			"IDLE", 5000, "RETRYCOUNT", retries).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewStreamConsumer(rdb, "orders", "billing", nil, log.DefaultLogger,
+		WithStreamBatch(2), WithStreamClaim(time.Second, time.Second), WithStreamDeadLetter("orders:dead", 3))
+	if err := c.deadLetterExhausted(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	dead, err := rdb.XRange(ctx, "orders:dead", "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Values["dlq_id"] != ids[4] || dead[0].Values["dlq_deliveries"] != "3" {
+		t.Fatalf("dead=%+v", dead)
+	}
+	pending, err := rdb.XPending(ctx, "orders", "billing").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 4 {
+		t.Fatalf("pending=%d", pending.Count)
+	}
+}
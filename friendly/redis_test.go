@@ -2,11 +2,27 @@ package friendly
 
 import (
 	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// newTestRedis 启动进程内的 miniredis（支持 Lua），测试结束时关闭
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return rdb, mr
+}
+
 func TestNewRedisCluster(t *testing.T) {
 	t.Run("empty seeds should return error", func(t *testing.T) {
 		c, cleanup, err := NewRedisCluster(context.Background(), log.DefaultLogger, nil, "", false)
@@ -36,3 +52,108 @@ func TestNewRedis(t *testing.T) {
 		}
 	})
 }
+
+type testRedisConf struct {
+	emptyRedisConf
+	poolSize    int32
+	dialTimeout *durationpb.Duration
+	db          int32
+	caFile      string
+	mode        string
+}
+
+func (c testRedisConf) GetPoolSize() int32                   { return c.poolSize }
+func (c testRedisConf) GetDialTimeout() *durationpb.Duration { return c.dialTimeout }
+func (c testRedisConf) GetDb() int32                         { return c.db }
+func (c testRedisConf) GetTlsCaFile() string                 { return c.caFile }
+func (c testRedisConf) GetConnectMode() string               { return c.mode }
+
+func TestResolveRedisTuning(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		tn := resolveRedisTuning(emptyRedisConf{}, 20*time.Second, false)
+		if tn.poolSize != 50 || tn.minIdleConns != 5 || tn.dialTimeout != 10*time.Second ||
+			tn.poolTimeout != 20*time.Second || tn.connMaxLifetime != 30*time.Minute || tn.lazy {
+			t.Fatalf("unexpected defaults: %+v", tn)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		conf := testRedisConf{poolSize: 8, dialTimeout: durationpb.New(time.Second), mode: RedisConnectLazy}
+		tn := resolveRedisTuning(conf, 20*time.Second, false)
+		if tn.poolSize != 8 || tn.dialTimeout != time.Second || !tn.lazy {
+			t.Fatalf("unexpected tuning: %+v", tn)
+		}
+	})
+
+	t.Run("fail fast overrides cluster default", func(t *testing.T) {
+		tn := resolveRedisTuning(testRedisConf{mode: RedisConnectFailFast}, 10*time.Second, true)
+		if tn.lazy {
+			t.Fatal("expected fail fast mode")
+		}
+	})
+}
+
+func TestBuildRedisTLS(t *testing.T) {
+	t.Run("no tls settings keeps base", func(t *testing.T) {
+		base := &tls.Config{ServerName: "base"}
+		got, err := buildRedisTLS(emptyRedisConf{}, base)
+		if err != nil || got != base {
+			t.Fatalf("expected base config, got=%v err=%v", got, err)
+		}
+	})
+
+	t.Run("missing ca file", func(t *testing.T) {
+		if _, err := buildRedisTLS(testRedisConf{caFile: "/not/exist.pem"}, nil); err == nil {
+			t.Fatal("expected error for missing ca file")
+		}
+	})
+
+	t.Run("invalid ca file", func(t *testing.T) {
+		f := filepath.Join(t.TempDir(), "ca.pem")
+		if err := os.WriteFile(f, []byte("not a pem"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := buildRedisTLS(testRedisConf{caFile: f}, nil); err == nil {
+			t.Fatal("expected error for invalid ca file")
+		}
+	})
+}
+
+func TestNewRedisWithConf(t *testing.T) {
+	dsn := "redis://127.0.0.1:1/0"
+
+	t.Run("lazy connect skips ping", func(t *testing.T) {
+		c, cleanup, err := NewRedisWithConf(context.Background(), log.DefaultLogger, dsn,
+			testRedisConf{mode: RedisConnectLazy, db: 3, poolSize: 4})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer cleanup()
+		if c.Options().DB != 3 || c.Options().PoolSize != 4 {
+			t.Fatalf("conf not applied: db=%d pool=%d", c.Options().DB, c.Options().PoolSize)
+		}
+	})
+
+	t.Run("fail fast returns ping error", func(t *testing.T) {
+		_, cleanup, err := NewRedisWithConf(context.Background(), log.DefaultLogger, dsn,
+			testRedisConf{dialTimeout: durationpb.New(100 * time.Millisecond)})
+		if err == nil {
+			t.Fatal("expected ping error")
+		}
+		if cleanup != nil {
+			cleanup()
+		}
+	})
+
+	t.Run("cluster fail fast returns ping error", func(t *testing.T) {
+		_, cleanup, err := NewRedisClusterWithConf(context.Background(), log.DefaultLogger,
+			[]string{"127.0.0.1:1"}, "", false,
+			testRedisConf{mode: RedisConnectFailFast, dialTimeout: durationpb.New(100 * time.Millisecond)})
+		if err == nil {
+			t.Fatal("expected cluster ping error")
+		}
+		if cleanup != nil {
+			cleanup()
+		}
+	})
+}
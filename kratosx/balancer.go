@@ -0,0 +1,109 @@
+package kratosx
+
+import (
+	"fmt"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/p2c"
+	"github.com/go-kratos/kratos/v2/selector/random"
+	"github.com/go-kratos/kratos/v2/selector/wrr"
+	"github.com/go-kratos/kratos/v2/transport"
+	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// SelectorKind 负载均衡算法
+type SelectorKind string
+
+const (
+	SelectorWRR    SelectorKind = "wrr"    // 平滑加权轮询
+	SelectorP2C    SelectorKind = "p2c"    // power of two choices，按 EWMA 延迟 / 在途请求数选择
+	SelectorRandom SelectorKind = "random" // 随机
+)
+
+// selectorBuilders kratos 的 grpc balancer 只能使用全局 selector，
+// 这里为每种算法单独注册 balancer，以便按服务选择不同算法
+var selectorBuilders = map[SelectorKind]selector.Builder{
+	SelectorWRR:    wrr.NewBuilder(),
+	SelectorP2C:    p2c.NewBuilder(),
+	SelectorRandom: random.NewBuilder(),
+}
+
+func init() {
+	for kind, b := range selectorBuilders {
+		balancer.Register(base.NewBalancerBuilder(
+			balancerName(kind),
+			&pickerBuilder{builder: b},
+			base.Config{HealthCheck: true},
+		))
+	}
+}
+
+func balancerName(kind SelectorKind) string {
+	return "kratosx_" + string(kind)
+}
+
+// balancerServiceConfig 选择 kind 对应 balancer 的 service config，健康检查配置与 kratos 默认一致
+func balancerServiceConfig(kind SelectorKind) string {
+	return fmt.Sprintf(`{"loadBalancingConfig": [{"%s":{}}],"healthCheckConfig":{"serviceName":""}}`, balancerName(kind))
+}
+
+// grpcNode selector 节点与对应的 SubConn
+type grpcNode struct {
+	selector.Node
+	subConn balancer.SubConn
+}
+
+type pickerBuilder struct {
+	builder selector.Builder
+}
+
+// Build 与 kratos 内置 balancer 相同：把 ready 的 SubConn 转为 selector 节点
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	nodes := make([]selector.Node, 0, len(info.ReadySCs))
+	for conn, sc := range info.ReadySCs {
+		ins, _ := sc.Address.Attributes.Value("rawServiceInstance").(*registry.ServiceInstance)
+		nodes = append(nodes, &grpcNode{
+			Node:    selector.NewNode("grpc", sc.Address.Addr, ins),
+			subConn: conn,
+		})
+	}
+	p := &picker{selector: b.builder.Build()}
+	p.selector.Apply(nodes)
+	return p
+}
+
+type picker struct {
+	selector selector.Selector
+}
+
+// Pick 选择节点，支持 kgrpc.WithNodeFilter 设置的节点过滤器
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	var filters []selector.NodeFilter
+	if tr, ok := transport.FromClientContext(info.Ctx); ok {
+		if gtr, ok := tr.(*kgrpc.Transport); ok {
+			filters = gtr.NodeFilters()
+		}
+	}
+
+	n, done, err := p.selector.Select(info.Ctx, selector.WithNodeFilter(filters...))
+	if err != nil {
+		return balancer.PickResult{}, err
+	}
+	return balancer.PickResult{
+		SubConn: n.(*grpcNode).subConn, //nolint:forcetypeassert
+		Done: func(di balancer.DoneInfo) {
+			done(info.Ctx, selector.DoneInfo{
+				Err:           di.Err,
+				BytesSent:     di.BytesSent,
+				BytesReceived: di.BytesReceived,
+				ReplyMD:       kgrpc.Trailer(di.Trailer),
+			})
+		},
+	}, nil
+}
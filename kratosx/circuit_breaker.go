@@ -0,0 +1,203 @@
+package kratosx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+// ReasonCircuitOpen 熔断打开时快速失败返回的 error reason（503）
+const ReasonCircuitOpen = "CIRCUIT_OPEN"
+
+// CircuitState 熔断器状态
+type CircuitState int32
+
+const (
+	CircuitClosed   CircuitState = iota // 正常放行并统计错误率
+	CircuitOpen                         // 快速失败
+	CircuitHalfOpen                     // 放行少量探测请求
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int32(s))
+	}
+}
+
+// CircuitBreaker 熔断参数，零值字段使用默认值；按 ServiceName 统计错误率
+type CircuitBreaker struct {
+	// Window 滑动统计窗口，默认 10s
+	Window time.Duration
+	// Buckets 窗口内的桶数，默认 10
+	Buckets int
+	// MinRequests 窗口内请求数达到该值才会熔断，默认 20
+	MinRequests int
+	// ErrorRatio 窗口内失败比例达到该值时熔断，默认 0.5
+	ErrorRatio float64
+	// OpenTimeout 熔断持续时间，之后进入半开状态，默认 5s
+	OpenTimeout time.Duration
+	// HalfOpenRequests 半开状态放行的探测请求数，全部成功后恢复，默认 3
+	HalfOpenRequests int
+	// IsFailure 判断调用是否计为失败，默认 kratos code >= 500
+	IsFailure func(err error) bool
+}
+
+func (c CircuitBreaker) withDefaults() CircuitBreaker {
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.Buckets <= 0 {
+		c.Buckets = 10
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.ErrorRatio <= 0 || c.ErrorRatio > 1 {
+		c.ErrorRatio = 0.5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 5 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 3
+	}
+	if c.IsFailure == nil {
+		c.IsFailure = isServerFailure
+	}
+	return c
+}
+
+func isServerFailure(err error) bool {
+	return err != nil && errors.FromError(err).Code >= 500
+}
+
+type breakerBucket struct {
+	slot          int64
+	total, failed int
+}
+
+type circuitBreaker struct {
+	cfg     CircuitBreaker
+	service ServiceName
+	now     func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	openedAt time.Time
+	buckets  []breakerBucket
+	probing  int // 半开状态已放行的探测数
+	probeOK  int // 半开状态已成功的探测数
+}
+
+func newCircuitBreaker(service ServiceName, cfg CircuitBreaker) *circuitBreaker {
+	cfg = cfg.withDefaults()
+	return &circuitBreaker{
+		cfg:     cfg,
+		service: service,
+		now:     time.Now,
+		buckets: make([]breakerBucket, cfg.Buckets),
+	}
+}
+
+// State 当前状态，熔断超时后即视为半开
+func (b *circuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.state
+}
+
+// refresh 熔断超时后进入半开状态，需持有锁
+func (b *circuitBreaker) refresh() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = CircuitHalfOpen
+		b.probing, b.probeOK = 0, 0
+	}
+}
+
+// allow 是否放行本次调用，放行后必须调用 record
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	switch b.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.probing >= b.cfg.HalfOpenRequests {
+			return false
+		}
+		b.probing++
+	}
+	return true
+}
+
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitClosed:
+		bucketLen := int64(b.cfg.Window) / int64(b.cfg.Buckets)
+		slot := b.now().UnixNano() / bucketLen
+		bk := &b.buckets[slot%int64(len(b.buckets))]
+		if bk.slot != slot {
+			*bk = breakerBucket{slot: slot}
+		}
+		bk.total++
+		if failed {
+			bk.failed++
+		}
+
+		var total, failures int
+		for _, x := range b.buckets {
+			if x.slot > slot-int64(len(b.buckets)) {
+				total += x.total
+				failures += x.failed
+			}
+		}
+		if total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.ErrorRatio {
+			b.open()
+		}
+	case CircuitHalfOpen:
+		if failed {
+			b.open()
+			return
+		}
+		if b.probeOK++; b.probeOK >= b.cfg.HalfOpenRequests {
+			b.state = CircuitClosed
+			clear(b.buckets)
+		}
+	}
+	// CircuitOpen：熔断前发出的调用，结果不再统计
+}
+
+func (b *circuitBreaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = b.now()
+}
+
+// middleware 熔断打开时直接返回 503 / CIRCUIT_OPEN，不再请求下游
+func (b *circuitBreaker) middleware() middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if !b.allow() {
+				return nil, errors.ServiceUnavailable(ReasonCircuitOpen,
+					fmt.Sprintf("circuit breaker is open for service %s", b.service))
+			}
+			reply, err := handler(ctx, req)
+			b.record(b.cfg.IsFailure(err))
+			return reply, err
+		}
+	}
+}
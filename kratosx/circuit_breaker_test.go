@@ -0,0 +1,95 @@
+package kratosx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newCircuitBreaker("order", CircuitBreaker{MinRequests: 4, ErrorRatio: 0.5, OpenTimeout: time.Second, HalfOpenRequests: 2})
+	b.now = func() time.Time { return now }
+
+	for _, failed := range []bool{false, false, true} {
+		if !b.allow() {
+			t.Fatal("closed breaker should allow")
+		}
+		b.record(failed)
+	}
+	if b.State() != CircuitClosed {
+		t.Fatal("below MinRequests should stay closed")
+	}
+	b.allow()
+	b.record(true)
+	if b.State() != CircuitOpen || b.allow() {
+		t.Fatal("2/4 failures should open")
+	}
+
+	now = now.Add(time.Second)
+	if b.State() != CircuitHalfOpen {
+		t.Fatalf("state=%v after open timeout", b.State())
+	}
+	if !b.allow() || !b.allow() || b.allow() {
+		t.Fatal("half-open should allow exactly HalfOpenRequests probes")
+	}
+	b.record(false)
+	b.record(true)
+	if b.State() != CircuitOpen {
+		t.Fatal("failed probe should reopen")
+	}
+
+	now = now.Add(time.Second)
+	b.allow()
+	b.allow()
+	b.record(false)
+	b.record(false)
+	if b.State() != CircuitClosed {
+		t.Fatal("successful probes should close")
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newCircuitBreaker("order", CircuitBreaker{Window: 10 * time.Second, MinRequests: 2})
+	b.now = func() time.Time { return now }
+
+	b.allow()
+	b.record(true)
+	now = now.Add(11 * time.Second) // 第一次失败已滑出窗口
+	b.allow()
+	b.record(true)
+	if b.State() != CircuitClosed {
+		t.Fatal("expired failures should not count")
+	}
+}
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	b := newCircuitBreaker("order", CircuitBreaker{MinRequests: 1})
+	calls := 0
+	h := b.middleware()(func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.ServiceUnavailable("DOWN", "down")
+	})
+
+	_, _ = h(context.Background(), nil)
+	_, err := h(context.Background(), nil)
+	if se := errors.FromError(err); se.Code != 503 || se.Reason != ReasonCircuitOpen {
+		t.Fatalf("err=%v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("open breaker should not call downstream, calls=%d", calls)
+	}
+
+	// 4xx 不计为失败
+	b = newCircuitBreaker("order", CircuitBreaker{MinRequests: 1})
+	h = b.middleware()(func(context.Context, interface{}) (interface{}, error) {
+		return nil, errors.NotFound("NOT_FOUND", "missing")
+	})
+	_, _ = h(context.Background(), nil)
+	if b.State() != CircuitClosed {
+		t.Fatal("client errors should not open the breaker")
+	}
+}
@@ -0,0 +1,78 @@
+package kratosx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// ClientLogger 客户端调用日志，与 ServerLogger 对应：
+// 调用完成后记录目标服务、operation、耗时、kratos code / reason 以及截断后的请求 / 响应，5xx 额外输出错误堆栈；
+// 脱敏、采样、跳过与截断规则见 LogPolicy
+func ClientLogger(logger log.Logger, opts ...LoggerOption) middleware.Middleware {
+	policy := newLogPolicy(opts...)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			var (
+				code      int32
+				reason    string
+				kind      string
+				operation string
+				target    string
+			)
+
+			startTime := time.Now()
+			if info, ok := transport.FromClientContext(ctx); ok {
+				kind = info.Kind().String()
+				operation = info.Operation()
+				target = info.Endpoint()
+			}
+			if policy.skip(operation) {
+				return handler(ctx, req)
+			}
+
+			reply, err = handler(ctx, req)
+			if err == nil && !policy.sampled() {
+				return
+			}
+			if se := errors.FromError(err); se != nil {
+				code = se.Code
+				reason = se.Reason
+			}
+
+			level, stack := extractError(err)
+			if code >= 500 {
+				_ = log.WithContext(ctx, logger).Log(level,
+					"kind", "client",
+					"component", kind,
+					"target", target,
+					"operation", operation,
+					"code", code,
+					"reason", reason,
+					"type", "Error Stack",
+					"msg", fmt.Sprintf("%+v", getErrCauseStack(err)),
+				)
+			}
+
+			_ = log.WithContext(ctx, logger).Log(level,
+				"kind", "client",
+				"component", kind,
+				"target", target,
+				"operation", operation,
+				"code", code,
+				"reason", reason,
+				"stack", stack,
+				"latency", time.Since(startTime).Seconds(),
+				"type", "Call Done",
+				"args", policy.body(operation, req),
+				"msg", policy.body(operation, reply),
+			)
+			return
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package kratosx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	pkgErr "github.com/pkg/errors"
+)
+
+type recordLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordLogger) Log(level log.Level, keyvals ...interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, level.String()+" "+fmt.Sprint(keyvals...))
+	return nil
+}
+
+func TestClientLogger(t *testing.T) {
+	tr := newTestTransport("/order.v1.Order/Get")
+	tr.endpoint = "discovery:///order"
+	ctx := transport.NewClientContext(context.Background(), tr)
+
+	t.Run("success", func(t *testing.T) {
+		logger := &recordLogger{}
+		reply, err := ClientLogger(logger)(func(context.Context, interface{}) (interface{}, error) {
+			return map[string]string{"id": "1"}, nil
+		})(ctx, map[string]string{"q": "x"})
+		if err != nil || reply == nil {
+			t.Fatalf("reply=%v err=%v", reply, err)
+		}
+		if len(logger.lines) != 1 {
+			t.Fatalf("lines=%v", logger.lines)
+		}
+		line := logger.lines[0]
+		for _, want := range []string{"INFO", "discovery:///order", "/order.v1.Order/Get", `"q":"x"`, `"id":"1"`} {
+			if !strings.Contains(line, want) {
+				t.Fatalf("line %q missing %q", line, want)
+			}
+		}
+	})
+
+	t.Run("server error logs stack", func(t *testing.T) {
+		logger := &recordLogger{}
+		_, err := ClientLogger(logger)(func(context.Context, interface{}) (interface{}, error) {
+			return nil, errors.InternalServer("DB", "down").WithCause(pkgErr.New("conn refused"))
+		})(ctx, nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if len(logger.lines) != 2 || !strings.Contains(logger.lines[0], "Error Stack") {
+			t.Fatalf("lines=%v", logger.lines)
+		}
+		if !strings.HasPrefix(logger.lines[1], "ERROR") || !strings.Contains(logger.lines[1], "DB") {
+			t.Fatalf("line=%q", logger.lines[1])
+		}
+	})
+
+	t.Run("client error has no stack line", func(t *testing.T) {
+		logger := &recordLogger{}
+		_, _ = ClientLogger(logger)(func(context.Context, interface{}) (interface{}, error) {
+			return nil, errors.NotFound("NOT_FOUND", "missing")
+		})(ctx, nil)
+		if len(logger.lines) != 1 {
+			t.Fatalf("lines=%v", logger.lines)
+		}
+	})
+}
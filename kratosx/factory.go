@@ -3,18 +3,18 @@ package kratosx
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/go-kratos/kratos/v2/selector/wrr"
 	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
 	"github.com/go-kratos/kratos/v2/transport/grpc/resolver/discovery"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/keepalive"
-
-	"github.com/jeffinity/singularity/nacosx"
 )
 
 const (
@@ -23,30 +23,73 @@ const (
 )
 
 type entry struct {
-	conn    *grpc.ClientConn
-	lastUse time.Time
+	conn        *grpc.ClientConn
+	lastUse     atomic.Int64 // UnixNano
+	idleTimeout time.Duration
+	instances   *atomic.Int64 // 服务发现解析出的可用实例数
 }
 
+func (e *entry) touch() { e.lastUse.Store(time.Now().UnixNano()) }
+
+func (e *entry) lastUsed() time.Time { return time.Unix(0, e.lastUse.Load()) }
+
 type ConnFactory struct {
 	discovery registry.Discovery
+	catalog   *ServiceCatalog             // 为 nil 时服务名即注册中心中的名称
+	options   connOptions                 // 全局参数
+	services  map[ServiceName]connOptions // 服务级参数（全局参数 + 覆盖）
 
-	mu    sync.RWMutex
-	cache map[string]*entry
+	mu       sync.RWMutex
+	cache    map[string]*entry
+	breakers map[ServiceName]*circuitBreaker // 熔断状态，跨重连保留
 }
 
-// NewConnFactory 使用默认的 WRR 负载均衡
+// NewConnFactory 创建连接工厂，d 可以是任意 registry.Discovery（如 *nacosx.Registry）；
+// 未通过 WithConnSelector 指定算法时使用默认的 WRR 负载均衡
 func NewConnFactory(
 	ctx context.Context,
-	d *nacosx.Registry,
+	d registry.Discovery,
+	opts ...ConnOption,
 ) (*ConnFactory, func(), error) {
 
+	o := defaultConnOptions()
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if err := o.buildTLS(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := o.applyCatalog(); err != nil {
+		return nil, nil, err
+	}
+
+	services := make(map[ServiceName]connOptions, len(o.services))
+	for svc, svcOpts := range o.services {
+		so := o.clone()
+		so.services = nil
+		for _, fn := range svcOpts {
+			fn(&so)
+		}
+		if so.tls != o.tls {
+			if err := so.buildTLS(); err != nil {
+				return nil, nil, errors.WithMessagef(err, "service %s", svc)
+			}
+		}
+		services[svc] = so
+	}
+
 	if selector.GlobalSelector() == nil {
 		selector.SetGlobalSelector(wrr.NewBuilder())
 	}
 
 	f := &ConnFactory{
 		discovery: d,
+		catalog:   o.catalog,
+		options:   o,
+		services:  services,
 		cache:     make(map[string]*entry),
+		breakers:  make(map[ServiceName]*circuitBreaker),
 	}
 
 	// janitor 协程：空闲回收 & ctx 退出
@@ -57,12 +100,117 @@ func NewConnFactory(
 	return f, cleanup, nil
 }
 
+// applyCatalog 校验服务级覆盖的服务名均已登记，并把目录项的参数放在服务级覆盖之前
+func (o *connOptions) applyCatalog() error {
+	if o.catalog == nil {
+		return nil
+	}
+	for svc := range o.services {
+		if _, err := o.catalog.Lookup(svc); err != nil {
+			return errors.WithMessage(err, "WithServiceConnOptions")
+		}
+	}
+	for _, name := range o.catalog.Names() {
+		spec, _ := o.catalog.Lookup(name)
+		specOpts := spec.connOptions()
+		if len(specOpts) == 0 {
+			continue
+		}
+		if o.services == nil {
+			o.services = make(map[ServiceName][]ConnOption)
+		}
+		o.services[name] = append(specOpts, o.services[name]...)
+	}
+	return nil
+}
+
+// target 服务在注册中心中的名称，配置了服务目录时校验服务已登记且提供 gRPC；
+// nacosx.Registry 按 endpoint 协议追加后缀，启用 TLS 时取 <name>.grpcs
+func (f *ConnFactory) target(service ServiceName) (string, error) {
+	if f.catalog == nil {
+		return service, nil
+	}
+	name, err := f.catalog.DiscoveryName(service, ProtocolGRPC)
+	if err != nil {
+		return "", err
+	}
+	if f.optionsFor(service).tlsConf != nil {
+		return GrpcsServiceName(service), nil
+	}
+	return name, nil
+}
+
+// buildTLS 由 ClientTLS 构造 *tls.Config
+func (o *connOptions) buildTLS() error {
+	o.tlsConf = nil
+	if o.tls == nil {
+		return nil
+	}
+	tc, err := o.tls.Config()
+	if err != nil {
+		return err
+	}
+	o.tlsConf = tc
+	return nil
+}
+
+// clientMiddleware 执行顺序：调用日志 → 重试 → 熔断 → 自定义中间件；
+// 调用日志在最外层，记录包含重试在内的完整耗时，每次重试都经过熔断统计
+func (f *ConnFactory) clientMiddleware(service ServiceName, o connOptions) []middleware.Middleware {
+	var ms []middleware.Middleware
+	if o.logger != nil {
+		ms = append(ms, ClientLogger(o.logger, WithLogPolicy(o.logPolicy)))
+	}
+	if o.retry != nil {
+		ms = append(ms, Retry(*o.retry))
+	}
+	if o.breaker != nil {
+		ms = append(ms, f.breakerFor(service, *o.breaker).middleware())
+	}
+	return append(ms, o.middleware...)
+}
+
+func (f *ConnFactory) breakerFor(service ServiceName, cfg CircuitBreaker) *circuitBreaker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.breakers[service]
+	if !ok {
+		b = newCircuitBreaker(service, cfg)
+		f.breakers[service] = b
+	}
+	return b
+}
+
+// CircuitState 返回 service 的熔断状态，未开启熔断或尚未调用时为 CircuitClosed
+func (f *ConnFactory) CircuitState(service ServiceName) CircuitState {
+	f.mu.RLock()
+	b, ok := f.breakers[service]
+	f.mu.RUnlock()
+	if !ok {
+		return CircuitClosed
+	}
+	return b.State()
+}
+
+// optionsFor 返回 service 生效的参数
+func (f *ConnFactory) optionsFor(service string) connOptions {
+	if o, ok := f.services[service]; ok {
+		return o
+	}
+	return f.options
+}
+
 // Conn 返回（或新建）到 service 的 *grpc.ClientConn
 func (f *ConnFactory) Conn(
 	ctx context.Context,
 	service string,
 ) (*grpc.ClientConn, error) {
 
+	target, err := f.target(service)
+	if err != nil {
+		return nil, err
+	}
+
 	f.mu.RLock()
 	e, ok := f.cache[service]
 	f.mu.RUnlock()
@@ -70,7 +218,7 @@ func (f *ConnFactory) Conn(
 	if ok && e != nil && e.conn != nil {
 		st := e.conn.GetState()
 		if st != connectivity.Shutdown {
-			e.lastUse = time.Now()
+			e.touch()
 			return e.conn, nil
 		}
 
@@ -80,43 +228,63 @@ func (f *ConnFactory) Conn(
 		f.mu.Unlock()
 	}
 
-	return f.dialAndCache(ctx, service)
+	return f.dialAndCache(ctx, service, target)
 }
 
 func (f *ConnFactory) dialAndCache(
 	ctx context.Context,
 	service string,
+	target string,
 ) (*grpc.ClientConn, error) {
 
-	conn, err := kgrpc.DialInsecure(
-		ctx,
-		kgrpc.WithEndpoint("discovery:///"+service),
-		kgrpc.WithTimeout(30*time.Second),
-		kgrpc.WithOptions(
-			grpc.WithIdleTimeout(idleTimeout),
-			grpc.WithKeepaliveParams(keepalive.ClientParameters{
-				Time:                30 * time.Second,
-				Timeout:             20 * time.Second,
-				PermitWithoutStream: true,
-			}),
-			grpc.WithResolvers(
-				discovery.NewBuilder(
-					f.discovery,
-					discovery.PrintDebugLog(false),
-					discovery.WithInsecure(true),
-				),
+	o := f.optionsFor(service)
+	instances := new(atomic.Int64)
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithIdleTimeout(o.idleTimeout),
+		grpc.WithKeepaliveParams(o.keepalive),
+		grpc.WithResolvers(
+			discovery.NewBuilder(
+				countingDiscovery{Discovery: f.discovery, secure: o.tlsConf != nil, count: instances},
+				discovery.PrintDebugLog(false),
+				// 明文只选取 grpc:// endpoint，TLS 只选取 grpcs:// endpoint
+				discovery.WithInsecure(o.tlsConf == nil),
 			),
 		),
+	}
+	if o.selector != "" {
+		// 在 kratos 默认的 service config 之后设置，覆盖其 balancer
+		dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(balancerServiceConfig(o.selector)))
+	}
+	dialOpts = append(dialOpts, o.dialOpts...)
+
+	clientOpts := []kgrpc.ClientOption{
+		kgrpc.WithEndpoint("discovery:///" + target),
+		kgrpc.WithTimeout(o.timeout),
+		kgrpc.WithMiddleware(f.clientMiddleware(service, o)...),
+		kgrpc.WithNodeFilter(o.nodeFilters...),
+		kgrpc.WithUnaryInterceptor(o.unaryInts...),
+		kgrpc.WithStreamInterceptor(o.streamInts...),
+		kgrpc.WithOptions(dialOpts...),
+	}
+
+	var (
+		conn *grpc.ClientConn
+		err  error
 	)
+	if o.tlsConf != nil {
+		conn, err = kgrpc.Dial(ctx, append(clientOpts, kgrpc.WithTLSConfig(o.tlsConf))...)
+	} else {
+		conn, err = kgrpc.DialInsecure(ctx, clientOpts...)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	e := &entry{conn: conn, idleTimeout: o.idleTimeout, instances: instances}
+	e.touch()
 	f.mu.Lock()
-	f.cache[service] = &entry{
-		conn:    conn,
-		lastUse: time.Now(),
-	}
+	f.cache[service] = e
 	f.mu.Unlock()
 	return conn, nil
 }
@@ -141,11 +309,12 @@ func (f *ConnFactory) janitor(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-tk.C:
-			cutoff := time.Now().Add(-cleanupPeriod)
+			now := time.Now()
 
 			f.mu.Lock()
 			for svc, e := range f.cache {
-				if e.lastUse.Before(cutoff) {
+				// 超过 IdleTimeout 再加 1min 彻底回收
+				if e.lastUsed().Before(now.Add(-e.idleTimeout - time.Minute)) {
 					_ = e.conn.Close()
 					delete(f.cache, svc)
 				}
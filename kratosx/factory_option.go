@@ -0,0 +1,171 @@
+package kratosx
+
+import (
+	"crypto/tls"
+	"slices"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/metadata"
+	"github.com/go-kratos/kratos/v2/middleware/tracing"
+	"github.com/go-kratos/kratos/v2/selector"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// ConnOption ConnFactory 的可选参数；既可作用于全部服务，也可通过 WithServiceConnOptions 只作用于单个服务
+type ConnOption func(o *connOptions)
+
+type connOptions struct {
+	tls         *ClientTLS
+	tlsConf     *tls.Config // 由 tls 构造，nil 时使用明文连接
+	timeout     time.Duration
+	idleTimeout time.Duration
+	keepalive   keepalive.ClientParameters
+	selector    SelectorKind // 为空时使用 kratos 全局 selector（默认 WRR）
+	logger      log.Logger   // ClientLogger 的输出，nil 时不记录调用日志
+	logPolicy   LogPolicy
+	breaker     *CircuitBreaker // nil 时不熔断
+	retry       *RetryPolicy    // nil 时不重试
+	healthCheck bool            // Warmup 时是否调用 grpc.health.v1 Check
+	nodeFilters []selector.NodeFilter
+	middleware  []middleware.Middleware
+	unaryInts   []grpc.UnaryClientInterceptor
+	streamInts  []grpc.StreamClientInterceptor
+	dialOpts    []grpc.DialOption
+
+	services map[ServiceName][]ConnOption // 服务级覆盖，仅在全局参数中生效
+	catalog  *ServiceCatalog              // 服务目录，仅在全局参数中生效
+}
+
+func defaultConnOptions() connOptions {
+	return connOptions{
+		timeout:     30 * time.Second,
+		idleTimeout: idleTimeout,
+		logger:      log.GetLogger(),
+		keepalive: keepalive.ClientParameters{
+			Time:                30 * time.Second,
+			Timeout:             20 * time.Second,
+			PermitWithoutStream: true,
+		},
+	}
+}
+
+// clone 复制一份，避免服务级覆盖修改共享的切片
+func (o connOptions) clone() connOptions {
+	o.nodeFilters = slices.Clone(o.nodeFilters)
+	o.middleware = slices.Clone(o.middleware)
+	o.unaryInts = slices.Clone(o.unaryInts)
+	o.streamInts = slices.Clone(o.streamInts)
+	o.dialOpts = slices.Clone(o.dialOpts)
+	return o
+}
+
+// WithConnTLS 使用 TLS / mTLS 连接下游服务，服务发现只选取 grpcs 协议的 endpoint
+func WithConnTLS(c *ClientTLS) ConnOption {
+	return func(o *connOptions) { o.tls = c }
+}
+
+// WithConnTimeout 单次调用的默认超时，默认 30s；批处理等慢服务可单独调大
+func WithConnTimeout(d time.Duration) ConnOption {
+	return func(o *connOptions) { o.timeout = d }
+}
+
+// WithConnIdleTimeout 连接空闲多久进入 IDLE，默认 5m；工厂在此基础上再空闲 1m 后回收连接
+func WithConnIdleTimeout(d time.Duration) ConnOption {
+	return func(o *connOptions) { o.idleTimeout = d }
+}
+
+// WithConnKeepalive 客户端 keepalive 参数，默认 30s / 20s / PermitWithoutStream
+func WithConnKeepalive(kp keepalive.ClientParameters) ConnOption {
+	return func(o *connOptions) { o.keepalive = kp }
+}
+
+// WithConnSelector 负载均衡算法；未设置时使用 kratos 全局 selector（默认 WRR）
+func WithConnSelector(kind SelectorKind) ConnOption {
+	return func(o *connOptions) { o.selector = kind }
+}
+
+// WithConnNodeFilter 追加节点过滤器，每次调用选择节点前执行（kratos 全局 selector 与 WithConnSelector 均生效）
+func WithConnNodeFilter(filters ...selector.NodeFilter) ConnOption {
+	return func(o *connOptions) { o.nodeFilters = append(o.nodeFilters, filters...) }
+}
+
+// WithConnRoute 按实例版本 / metadata 路由与灰度，见 RoutePolicy
+func WithConnRoute(p RoutePolicy) ConnOption {
+	return WithConnNodeFilter(RouteFilter(p))
+}
+
+// WithConnLogger 调用日志（ClientLogger）的输出，默认 log.GetLogger()；传 nil 关闭调用日志
+func WithConnLogger(logger log.Logger) ConnOption {
+	return func(o *connOptions) { o.logger = logger }
+}
+
+// WithConnLogPolicy 调用日志的脱敏 / 采样 / 截断策略
+func WithConnLogPolicy(p LogPolicy) ConnOption {
+	return func(o *connOptions) { o.logPolicy = p }
+}
+
+// WithConnCircuitBreaker 按服务熔断：错误率超过阈值后快速失败（503 / CIRCUIT_OPEN），传 nil 关闭
+func WithConnCircuitBreaker(cb *CircuitBreaker) ConnOption {
+	return func(o *connOptions) { o.breaker = cb }
+}
+
+// WithConnRetry 对幂等 operation 按策略重试，传 nil 关闭
+func WithConnRetry(p *RetryPolicy) ConnOption {
+	return func(o *connOptions) { o.retry = p }
+}
+
+// WithConnHealthCheck Warmup 在连接 Ready 后再调用标准 grpc.health.v1.Health/Check，要求返回 SERVING
+func WithConnHealthCheck() ConnOption {
+	return func(o *connOptions) { o.healthCheck = true }
+}
+
+// WithConnMiddleware 追加客户端中间件（在 ClientLogger 之后执行），可配合 DefaultClientMiddleware 使用
+func WithConnMiddleware(ms ...middleware.Middleware) ConnOption {
+	return func(o *connOptions) { o.middleware = append(o.middleware, ms...) }
+}
+
+// WithConnInterceptor 追加 gRPC 原生拦截器，在 kratos 中间件之后执行
+func WithConnInterceptor(unary ...grpc.UnaryClientInterceptor) ConnOption {
+	return func(o *connOptions) { o.unaryInts = append(o.unaryInts, unary...) }
+}
+
+// WithConnStreamInterceptor 追加 gRPC 原生流式拦截器
+func WithConnStreamInterceptor(stream ...grpc.StreamClientInterceptor) ConnOption {
+	return func(o *connOptions) { o.streamInts = append(o.streamInts, stream...) }
+}
+
+// WithConnDialOptions 追加 grpc.DialOption，最后应用，可覆盖前面的设置
+func WithConnDialOptions(opts ...grpc.DialOption) ConnOption {
+	return func(o *connOptions) { o.dialOpts = append(o.dialOpts, opts...) }
+}
+
+// WithConnCatalog 通过服务目录解析服务名：Conn / GetGrpcClient 使用逻辑服务名（如 order），
+// 按目录解析为注册中心中的名称（order.grpc）并应用目录中的超时与 metadata 要求；
+// 未登记的服务在 NewConnFactory（WithServiceConnOptions）、NewServiceClient 或调用时报错
+func WithConnCatalog(c *ServiceCatalog) ConnOption {
+	return func(o *connOptions) { o.catalog = c }
+}
+
+// WithServiceConnOptions 为单个服务覆盖参数，在全局参数之后应用；
+// 中间件 / 拦截器为追加，其余为覆盖
+func WithServiceConnOptions(service ServiceName, opts ...ConnOption) ConnOption {
+	return func(o *connOptions) {
+		// 服务级参数由 NewConnFactory 单独收集，这里只做登记
+		if o.services == nil {
+			o.services = make(map[ServiceName][]ConnOption)
+		}
+		o.services[service] = append(o.services[service], opts...)
+	}
+}
+
+// DefaultClientMiddleware 常用客户端中间件：链路追踪、metadata 透传；
+// 调用日志由 ConnFactory 默认开启（见 WithConnLogger），其他 kratos client 可另行追加 ClientLogger
+func DefaultClientMiddleware() []middleware.Middleware {
+	return []middleware.Middleware{
+		tracing.Client(),
+		metadata.Client(),
+	}
+}
@@ -0,0 +1,123 @@
+package kratosx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/registry"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+func noopMiddleware(h middleware.Handler) middleware.Handler { return h }
+
+func TestConnFactoryServiceOverrides(t *testing.T) {
+	f, cleanup, err := NewConnFactory(context.Background(), nil,
+		WithConnTimeout(5*time.Second),
+		WithConnMiddleware(noopMiddleware),
+		WithServiceConnOptions("batch",
+			WithConnTimeout(10*time.Minute),
+			WithConnSelector(SelectorP2C),
+			WithConnMiddleware(noopMiddleware),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	base := f.optionsFor("order")
+	if base.timeout != 5*time.Second || base.selector != "" || len(base.middleware) != 1 {
+		t.Fatalf("base options=%+v", base)
+	}
+	if base.idleTimeout != idleTimeout || !base.keepalive.PermitWithoutStream {
+		t.Fatalf("base defaults=%+v", base)
+	}
+
+	batch := f.optionsFor("batch")
+	if batch.timeout != 10*time.Minute || batch.selector != SelectorP2C || len(batch.middleware) != 2 {
+		t.Fatalf("batch options=%+v", batch)
+	}
+	if len(f.optionsFor("order").middleware) != 1 {
+		t.Fatal("service override should not modify global middleware")
+	}
+}
+
+func TestConnFactoryServiceTLSError(t *testing.T) {
+	_, _, err := NewConnFactory(context.Background(), nil,
+		WithServiceConnOptions("secure", WithConnTLS(&ClientTLS{CAFile: "/not/exist.pem"})),
+	)
+	if err == nil {
+		t.Fatal("invalid service tls config should fail")
+	}
+}
+
+func TestBalancerServiceConfig(t *testing.T) {
+	for kind := range selectorBuilders {
+		var cfg map[string]interface{}
+		if err := json.Unmarshal([]byte(balancerServiceConfig(kind)), &cfg); err != nil {
+			t.Fatalf("%s: invalid service config: %v", kind, err)
+		}
+		if balancer.Get(balancerName(kind)) == nil {
+			t.Fatalf("%s: balancer not registered", kind)
+		}
+	}
+}
+
+type fakeSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+func TestPickerSelectsReadySubConn(t *testing.T) {
+	sc := &fakeSubConn{name: "a"}
+	ins := &registry.ServiceInstance{ID: "1", Name: "order", Endpoints: []string{"grpc://127.0.0.1:9000"}}
+	pb := &pickerBuilder{builder: selectorBuilders[SelectorRandom]}
+
+	if _, err := pb.Build(base.PickerBuildInfo{}).Pick(balancer.PickInfo{Ctx: context.Background()}); err == nil {
+		t.Fatal("picker without ready subconn should fail")
+	}
+
+	p := pb.Build(base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+		sc: {Address: resolver.Address{
+			Addr:       "127.0.0.1:9000",
+			Attributes: attributes.New("rawServiceInstance", ins),
+		}},
+	}})
+	res, err := p.Pick(balancer.PickInfo{Ctx: context.Background()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SubConn != sc {
+		t.Fatalf("picked %v", res.SubConn)
+	}
+	res.Done(balancer.DoneInfo{})
+}
+
+func TestConnOptionsClientMiddleware(t *testing.T) {
+	f := &ConnFactory{breakers: make(map[ServiceName]*circuitBreaker)}
+	o := defaultConnOptions()
+	o.middleware = []middleware.Middleware{noopMiddleware}
+	if got := len(f.clientMiddleware("order", o)); got != 2 {
+		t.Fatalf("default should prepend ClientLogger, got %d", got)
+	}
+
+	WithConnLogger(nil)(&o)
+	if got := len(f.clientMiddleware("order", o)); got != 1 {
+		t.Fatalf("nil logger should disable ClientLogger, got %d", got)
+	}
+
+	WithConnRetry(&RetryPolicy{})(&o)
+	WithConnCircuitBreaker(&CircuitBreaker{})(&o)
+	if got := len(f.clientMiddleware("order", o)); got != 3 {
+		t.Fatalf("retry and breaker should be added, got %d", got)
+	}
+	if len(f.breakers) != 1 || f.CircuitState("order") != CircuitClosed {
+		t.Fatalf("breakers=%v", f.breakers)
+	}
+}
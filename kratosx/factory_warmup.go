@@ -0,0 +1,146 @@
+package kratosx
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ConnStatus ConnFactory 中单个服务的连接快照
+type ConnStatus struct {
+	State     connectivity.State
+	LastUse   time.Time
+	Instances int // 服务发现解析出的可用实例数（按 grpc / grpcs 协议过滤、去重）
+	Circuit   CircuitState
+}
+
+// Status 返回已建立连接的各服务快照
+func (f *ConnFactory) Status() map[ServiceName]ConnStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	out := make(map[ServiceName]ConnStatus, len(f.cache))
+	for svc, e := range f.cache {
+		st := ConnStatus{
+			State:     e.conn.GetState(),
+			LastUse:   e.lastUsed(),
+			Instances: int(e.instances.Load()),
+			Circuit:   CircuitClosed,
+		}
+		if b, ok := f.breakers[svc]; ok {
+			st.Circuit = b.State()
+		}
+		out[svc] = st
+	}
+	return out
+}
+
+// Warmup 并发建立到 services 的连接并等待 Ready；开启 WithConnHealthCheck 的服务还需 health Check 返回 SERVING。
+// 任一服务失败即返回错误，超时由 ctx 控制，适合在启动探针中阻塞等待关键依赖
+func (f *ConnFactory) Warmup(ctx context.Context, services ...ServiceName) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, svc := range services {
+		eg.Go(func() error {
+			return errors.WithMessagef(f.warmup(ctx, svc), "warmup %s", svc)
+		})
+	}
+	return eg.Wait()
+}
+
+func (f *ConnFactory) warmup(ctx context.Context, service ServiceName) error {
+	conn, err := f.Conn(ctx, service)
+	if err != nil {
+		return err
+	}
+	if err := waitForReady(ctx, conn); err != nil {
+		f.mu.RLock()
+		e, ok := f.cache[service]
+		f.mu.RUnlock()
+		if ok {
+			return errors.WithMessagef(err, "%d instances resolved", e.instances.Load())
+		}
+		return err
+	}
+	if !f.optionsFor(service).healthCheck {
+		return nil
+	}
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return errors.Wrap(err, "health check")
+	}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return errors.Errorf("health status %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// waitForReady 主动连接并等待 Ready，ctx 结束时返回 ctx 的错误及最后的连接状态
+func waitForReady(ctx context.Context, cc *grpc.ClientConn) error {
+	cc.Connect()
+	for {
+		s := cc.GetState()
+		switch s {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Shutdown:
+			return errors.New("connection is shut down")
+		}
+		if !cc.WaitForStateChange(ctx, s) {
+			return errors.Wrapf(ctx.Err(), "wait for ready, last state %s", s)
+		}
+	}
+}
+
+// countingDiscovery 记录 resolver 收到的可用实例数，过滤规则与 kratos discovery resolver 一致
+type countingDiscovery struct {
+	registry.Discovery
+	secure bool
+	count  *atomic.Int64
+}
+
+func (d countingDiscovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	w, err := d.Discovery.Watch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return countingWatcher{Watcher: w, d: d}, nil
+}
+
+type countingWatcher struct {
+	registry.Watcher
+	d countingDiscovery
+}
+
+func (w countingWatcher) Next() ([]*registry.ServiceInstance, error) {
+	ins, err := w.Watcher.Next()
+	if err == nil {
+		w.d.count.Store(int64(countEndpoints(ins, w.d.secure)))
+	}
+	return ins, err
+}
+
+func countEndpoints(ins []*registry.ServiceInstance, secure bool) int {
+	scheme := "grpc"
+	if secure {
+		scheme = "grpcs"
+	}
+	seen := make(map[string]struct{}, len(ins))
+	for _, in := range ins {
+		for _, e := range in.Endpoints {
+			if u, err := url.Parse(e); err == nil && u.Scheme == scheme {
+				seen[u.Host] = struct{}{}
+				break
+			}
+		}
+	}
+	return len(seen)
+}
@@ -0,0 +1,131 @@
+package kratosx
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// staticDiscovery 返回固定实例，首次 Next 后阻塞直到 Stop
+type staticDiscovery struct {
+	instances []*registry.ServiceInstance
+}
+
+func (d staticDiscovery) GetService(context.Context, string) ([]*registry.ServiceInstance, error) {
+	return d.instances, nil
+}
+
+func (d staticDiscovery) Watch(ctx context.Context, _ string) (registry.Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &staticWatcher{ctx: ctx, cancel: cancel, instances: d.instances}, nil
+}
+
+type staticWatcher struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	instances []*registry.ServiceInstance
+	sent      bool
+}
+
+func (w *staticWatcher) Next() ([]*registry.ServiceInstance, error) {
+	if !w.sent {
+		w.sent = true
+		return w.instances, nil
+	}
+	<-w.ctx.Done()
+	return nil, w.ctx.Err()
+}
+
+func (w *staticWatcher) Stop() error {
+	w.cancel()
+	return nil
+}
+
+func startHealthServer(t *testing.T, status grpc_health_v1.HealthCheckResponse_ServingStatus) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("", status)
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestConnFactoryWarmup(t *testing.T) {
+	serving := startHealthServer(t, grpc_health_v1.HealthCheckResponse_SERVING)
+	notServing := startHealthServer(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	d := staticDiscovery{instances: []*registry.ServiceInstance{
+		{ID: "1", Name: "order", Endpoints: []string{"http://" + serving, "grpc://" + serving}},
+		{ID: "2", Name: "order", Endpoints: []string{"grpc://" + serving}}, // 重复 endpoint
+		{ID: "3", Name: "order", Endpoints: []string{"grpcs://" + notServing}},
+	}}
+	ctx := context.Background()
+
+	f, cleanup, err := NewConnFactory(ctx, d, WithConnLogger(nil), WithConnHealthCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := f.Warmup(wctx, "order"); err != nil {
+		t.Fatal(err)
+	}
+
+	st, ok := f.Status()["order"]
+	if !ok {
+		t.Fatal("missing status")
+	}
+	if st.State != connectivity.Ready || st.Instances != 1 || st.Circuit != CircuitClosed || st.LastUse.IsZero() {
+		t.Fatalf("status=%+v", st)
+	}
+}
+
+func TestConnFactoryWarmupHealthFailure(t *testing.T) {
+	addr := startHealthServer(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	d := staticDiscovery{instances: []*registry.ServiceInstance{
+		{ID: "1", Name: "billing", Endpoints: []string{"grpc://" + addr}},
+	}}
+	f, cleanup, err := NewConnFactory(context.Background(), d,
+		WithConnLogger(nil),
+		WithServiceConnOptions("billing", WithConnHealthCheck()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	// kratos 客户端默认开启 gRPC 客户端健康检查，NOT_SERVING 的实例不会进入 Ready
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := f.Warmup(ctx, "billing"); err == nil {
+		t.Fatal("NOT_SERVING should fail warmup")
+	}
+}
+
+func TestCountEndpoints(t *testing.T) {
+	ins := []*registry.ServiceInstance{
+		{Endpoints: []string{"grpc://a:1", "grpcs://a:2"}},
+		{Endpoints: []string{"grpcs://b:2"}},
+		{Endpoints: []string{"http://c:3"}},
+	}
+	if got := countEndpoints(ins, false); got != 1 {
+		t.Fatalf("insecure count=%d", got)
+	}
+	if got := countEndpoints(ins, true); got != 2 {
+		t.Fatalf("secure count=%d", got)
+	}
+}
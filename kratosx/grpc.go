@@ -8,10 +8,10 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/proxy"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// NewGrpcConn 默认使用明文连接；传入 GrpcTLSDialOption 生成的 DialOption 可改用 TLS / mTLS
 func NewGrpcConn(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	cc, err := grpc.NewClient(
 		target,
@@ -25,6 +25,7 @@ func NewGrpcConn(target string, opts ...grpc.DialOption) (*grpc.ClientConn, erro
 	return cc, nil
 }
 
+// NewGrpcConnAndTest 建立连接并等待 Ready，超时 / 取消时关闭连接并返回错误
 func NewGrpcConnAndTest(ctx context.Context, target string, timeout time.Duration, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -34,18 +35,11 @@ func NewGrpcConnAndTest(ctx context.Context, target string, timeout time.Duratio
 	if err != nil {
 		return nil, err
 	}
-	cc.Connect()
-
-	for {
-		s := cc.GetState()
-		if s == connectivity.Ready {
-			return cc, nil // 成功
-		}
-		if !cc.WaitForStateChange(timeoutCtx, s) {
-			_ = cc.Close()
-			return nil, errors.WithStack(err) // 超时 / 取消
-		}
+	if err := waitForReady(timeoutCtx, cc); err != nil {
+		_ = cc.Close()
+		return nil, errors.WithMessagef(err, "connect %s", target)
 	}
+	return cc, nil
 }
 
 func NewGrpcConnWithSS(proxyAddr, target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
@@ -66,6 +60,7 @@ func NewGrpcConnWithSS(proxyAddr, target string, opts ...grpc.DialOption) (*grpc
 
 type NewClientFunc[C any] func(grpc.ClientConnInterface) C
 
+// GetGrpcClient 通过 ConnFactory 获取 service 的客户端；配置了服务目录时 service 为目录中的逻辑服务名
 func GetGrpcClient[C any](ctx context.Context, factory *ConnFactory, service ServiceName, fn NewClientFunc[C]) (C, error) {
 	conn, err := factory.Conn(ctx, service)
 	if err != nil {
@@ -74,3 +69,24 @@ func GetGrpcClient[C any](ctx context.Context, factory *ConnFactory, service Ser
 	}
 	return fn(conn), nil
 }
+
+// ServiceClient 绑定服务名的类型化客户端，适合在 wire provider 中创建
+type ServiceClient[C any] struct {
+	factory *ConnFactory
+	service ServiceName
+	fn      NewClientFunc[C]
+}
+
+// NewServiceClient 创建类型化客户端；ConnFactory 配置了服务目录时校验服务已登记且提供 gRPC，
+// 服务名拼写错误在启动时即报错
+func NewServiceClient[C any](factory *ConnFactory, service ServiceName, fn NewClientFunc[C]) (*ServiceClient[C], error) {
+	if _, err := factory.target(service); err != nil {
+		return nil, err
+	}
+	return &ServiceClient[C]{factory: factory, service: service, fn: fn}, nil
+}
+
+// Get 返回客户端，连接按需建立并由 ConnFactory 缓存
+func (c *ServiceClient[C]) Get(ctx context.Context) (C, error) {
+	return GetGrpcClient(ctx, c.factory, c.service, c.fn)
+}
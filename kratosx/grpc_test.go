@@ -0,0 +1,44 @@
+package kratosx
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestNewGrpcConnAndTestTimeout(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	cc, err := NewGrpcConnAndTest(context.Background(), addr, 200*time.Millisecond)
+	if err == nil || cc != nil {
+		t.Fatalf("unreachable target should fail, cc=%v err=%v", cc, err)
+	}
+}
+
+func TestNewServiceClient(t *testing.T) {
+	c, err := NewServiceCatalog(ServiceSpec{Name: "order"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, cleanup, err := NewConnFactory(context.Background(), nil, WithConnCatalog(c), WithConnLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	newClient := func(cc grpc.ClientConnInterface) grpc.ClientConnInterface { return cc }
+	if _, err := NewServiceClient(f, "ordr", newClient); err == nil {
+		t.Fatal("misspelled service should fail at construction")
+	}
+	if _, err := NewServiceClient(f, "order", newClient); err != nil {
+		t.Fatal(err)
+	}
+}
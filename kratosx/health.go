@@ -0,0 +1,230 @@
+package kratosx
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	HealthzPath = "/healthz" // 存活探针：进程可响应即返回 200
+	ReadyzPath  = "/readyz"  // 就绪探针：未关闭且全部依赖检查通过时返回 200，否则 503
+)
+
+// HealthChecker 依赖检查，返回 nil 表示可用
+type HealthChecker func(ctx context.Context) error
+
+type healthOptions struct {
+	timeout  time.Duration
+	interval time.Duration
+	logger   log.Logger
+}
+
+// HealthOption NewHealth 的可选参数
+type HealthOption func(o *healthOptions)
+
+// WithHealthTimeout 单次检查（全部 checker 并发执行）的超时，默认 3s
+func WithHealthTimeout(d time.Duration) HealthOption {
+	return func(o *healthOptions) { o.timeout = d }
+}
+
+// WithHealthInterval 后台刷新 gRPC 健康状态的间隔，默认 10s
+func WithHealthInterval(d time.Duration) HealthOption {
+	return func(o *healthOptions) { o.interval = d }
+}
+
+// WithHealthLogger 状态变化日志的输出，默认 log.GetLogger()
+func WithHealthLogger(logger log.Logger) HealthOption {
+	return func(o *healthOptions) { o.logger = logger }
+}
+
+// HealthReport 一次检查的结果
+type HealthReport struct {
+	Serving bool              `json:"serving"`
+	Checks  map[string]string `json:"checks,omitempty"` // checker 名称 -> "ok" 或错误信息
+}
+
+type namedChecker struct {
+	name  string
+	check HealthChecker
+}
+
+var _ transport.Server = (*Health)(nil)
+
+// Health 健康检查子系统：聚合依赖检查，对外提供 grpc.health.v1.Health 与 /healthz、/readyz。
+// Health 实现 transport.Server，通过 kratos.Server(...) 启动后定期刷新 gRPC 状态；
+// 配合 kratos.BeforeStop(h.Shutdown) 在注销服务之前切换为 NOT_SERVING，便于负载均衡摘流
+type Health struct {
+	opts healthOptions
+	log  *log.Helper
+	srv  *health.Server
+
+	mu       sync.RWMutex
+	checkers []namedChecker
+	serving  bool // 上次检查结果，用于记录状态变化
+
+	shutdown atomic.Bool
+}
+
+// NewHealth 创建健康检查，首次检查通过前 gRPC 状态为 NOT_SERVING
+func NewHealth(opts ...HealthOption) *Health {
+	o := healthOptions{
+		timeout:  3 * time.Second,
+		interval: 10 * time.Second,
+		logger:   log.GetLogger(),
+	}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	h := &Health{
+		opts: o,
+		log:  log.NewHelper(log.With(o.logger, "module", "kratosx/health")),
+		srv:  health.NewServer(),
+	}
+	h.srv.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	return h
+}
+
+// AddChecker 添加依赖检查，name 用于报告与日志
+func (h *Health) AddChecker(name string, c HealthChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers = append(h.checkers, namedChecker{name: name, check: c})
+}
+
+// RegisterGRPC 在 gRPC 服务上注册 grpc.health.v1.Health；
+// 服务需以 kgrpc.CustomHealth() 创建，否则与 kratos 内置的 health 服务重复注册
+func (h *Health) RegisterGRPC(s *kgrpc.Server) {
+	grpc_health_v1.RegisterHealthServer(s, h.srv)
+}
+
+// RegisterHTTP 在 HTTP 服务上注册 /healthz 与 /readyz
+func (h *Health) RegisterHTTP(s *khttp.Server) {
+	s.HandleFunc(HealthzPath, h.healthz)
+	s.HandleFunc(ReadyzPath, h.readyz)
+}
+
+// Check 并发执行全部 checker 并同步 gRPC 状态；关闭后始终返回 NOT_SERVING
+func (h *Health) Check(ctx context.Context) HealthReport {
+	if h.shutdown.Load() {
+		return HealthReport{Checks: map[string]string{"shutdown": "shutting down"}}
+	}
+
+	h.mu.RLock()
+	checkers := h.checkers
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, h.opts.timeout)
+	defer cancel()
+
+	errs := make([]error, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{Serving: true, Checks: make(map[string]string, len(checkers))}
+	var failed []string
+	for i, c := range checkers {
+		if errs[i] != nil {
+			report.Serving = false
+			report.Checks[c.name] = errs[i].Error()
+			failed = append(failed, c.name)
+		} else {
+			report.Checks[c.name] = "ok"
+		}
+	}
+	sort.Strings(failed)
+	h.setServing(report.Serving, failed)
+	return report
+}
+
+func (h *Health) setServing(serving bool, failed []string) {
+	h.mu.Lock()
+	changed := h.serving != serving
+	h.serving = serving
+	h.mu.Unlock()
+
+	status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	// Shutdown 之后 health.Server 忽略状态更新
+	h.srv.SetServingStatus("", status)
+
+	if changed {
+		if serving {
+			h.log.Info("health status changed to SERVING")
+		} else {
+			h.log.Warnf("health status changed to NOT_SERVING, failed checks: %v", failed)
+		}
+	}
+}
+
+// Shutdown 切换为 NOT_SERVING 且不再恢复；签名与 kratos.BeforeStop 一致，应在注销服务之前调用
+func (h *Health) Shutdown(context.Context) error {
+	if h.shutdown.Swap(true) {
+		return nil
+	}
+	h.srv.Shutdown()
+	h.log.Info("health status changed to NOT_SERVING for shutdown")
+	return nil
+}
+
+// Start 立即检查一次，之后按间隔刷新 gRPC 状态，直到 ctx 结束
+func (h *Health) Start(ctx context.Context) error {
+	h.Check(ctx)
+	tk := time.NewTicker(h.opts.interval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tk.C:
+			if h.shutdown.Load() {
+				return nil
+			}
+			h.Check(ctx)
+		}
+	}
+}
+
+// Stop 等同于 Shutdown
+func (h *Health) Stop(ctx context.Context) error {
+	return h.Shutdown(ctx)
+}
+
+func (h *Health) healthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (h *Health) readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+	bs, _ := sonic.ConfigFastest.Marshal(report)
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Serving {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(bs)
+}
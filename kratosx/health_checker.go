@@ -0,0 +1,45 @@
+package kratosx
+
+import (
+	"context"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// DBChecker 检查 gorm 连接池（如 pgx.NewPostgres 返回的 *gorm.DB）
+func DBChecker(db *gorm.DB) HealthChecker {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return errors.WithMessage(err, "get sql db")
+		}
+		return errors.WithMessage(sqlDB.PingContext(ctx), "ping db")
+	}
+}
+
+// RedisChecker 检查 Redis（friendly.NewRedis / NewRedisCluster / NewRedisUniversal 等返回的客户端）
+func RedisChecker(rdb redis.Cmdable) HealthChecker {
+	return func(ctx context.Context) error {
+		return errors.WithMessage(rdb.Ping(ctx).Err(), "ping redis")
+	}
+}
+
+// NacosChecker 检查 Nacos 命名客户端（nacosx.NewNamingClient）与服务端的连接
+func NacosChecker(nc naming_client.INamingClient) HealthChecker {
+	return func(context.Context) error {
+		if !nc.ServerHealthy() {
+			return errors.New("nacos server unhealthy")
+		}
+		return nil
+	}
+}
+
+// ConnFactoryChecker 检查下游服务连接可用，语义同 ConnFactory.Warmup
+func ConnFactoryChecker(f *ConnFactory, services ...ServiceName) HealthChecker {
+	return func(ctx context.Context) error {
+		return f.Warmup(ctx, services...)
+	}
+}
@@ -0,0 +1,67 @@
+package kratosx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestDBChecker(t *testing.T) {
+	db, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 user=x dbname=x sslmode=disable connect_timeout=1"),
+		&gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DBChecker(db)(context.Background()); err == nil {
+		t.Fatal("unreachable db should fail")
+	}
+}
+
+func TestRedisChecker(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	defer func() { _ = rdb.Close() }()
+	if err := RedisChecker(rdb)(context.Background()); err == nil {
+		t.Fatal("unreachable redis should fail")
+	}
+}
+
+type fakeNamingClient struct {
+	naming_client.INamingClient
+	healthy bool
+}
+
+func (c fakeNamingClient) ServerHealthy() bool { return c.healthy }
+
+func TestNacosChecker(t *testing.T) {
+	if err := NacosChecker(fakeNamingClient{healthy: true})(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := NacosChecker(fakeNamingClient{})(context.Background()); err == nil {
+		t.Fatal("unhealthy nacos should fail")
+	}
+}
+
+func TestConnFactoryChecker(t *testing.T) {
+	addr := startHealthServer(t, grpc_health_v1.HealthCheckResponse_SERVING)
+	d := staticDiscovery{instances: []*registry.ServiceInstance{
+		{ID: "1", Name: "order", Endpoints: []string{"grpc://" + addr}},
+	}}
+	f, cleanup, err := NewConnFactory(context.Background(), d, WithConnLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ConnFactoryChecker(f, "order")(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
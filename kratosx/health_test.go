@@ -0,0 +1,114 @@
+package kratosx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func grpcHealthStatus(t *testing.T, h *Health) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := h.srv.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.GetStatus()
+}
+
+func TestHealthCheck(t *testing.T) {
+	h := NewHealth(WithHealthLogger(log.DefaultLogger))
+	if grpcHealthStatus(t, h) != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatal("should not serve before first check")
+	}
+
+	var dbErr error
+	h.AddChecker("db", func(context.Context) error { return dbErr })
+	h.AddChecker("redis", func(context.Context) error { return nil })
+
+	report := h.Check(context.Background())
+	if !report.Serving || report.Checks["db"] != "ok" || grpcHealthStatus(t, h) != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("report=%+v", report)
+	}
+
+	dbErr = errors.New("connection refused")
+	report = h.Check(context.Background())
+	if report.Serving || report.Checks["db"] != "connection refused" || report.Checks["redis"] != "ok" {
+		t.Fatalf("report=%+v", report)
+	}
+	if grpcHealthStatus(t, h) != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatal("failed checker should flip gRPC status")
+	}
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	h := NewHealth(WithHealthTimeout(50 * time.Millisecond))
+	h.AddChecker("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if h.Check(context.Background()).Serving {
+		t.Fatal("timed out checker should fail")
+	}
+}
+
+func TestHealthShutdown(t *testing.T) {
+	h := NewHealth()
+	h.Check(context.Background())
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if h.Check(context.Background()).Serving || grpcHealthStatus(t, h) != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatal("should stay NOT_SERVING after shutdown")
+	}
+}
+
+func TestHealthHTTP(t *testing.T) {
+	h := NewHealth()
+	var depErr error
+	h.AddChecker("dep", func(context.Context) error { return depErr })
+
+	do := func(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := do(h.readyz, ReadyzPath); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"serving":true`) {
+		t.Fatalf("readyz=%d %s", rec.Code, rec.Body.String())
+	}
+	depErr = errors.New("down")
+	if rec := do(h.readyz, ReadyzPath); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "down") {
+		t.Fatalf("readyz=%d %s", rec.Code, rec.Body.String())
+	}
+
+	_ = h.Shutdown(context.Background())
+	if rec := do(h.healthz, HealthzPath); rec.Code != http.StatusOK {
+		t.Fatalf("healthz=%d during shutdown", rec.Code)
+	}
+}
+
+func TestHealthStart(t *testing.T) {
+	h := NewHealth(WithHealthInterval(10 * time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- h.Start(ctx) }()
+
+	deadline := time.Now().Add(time.Second)
+	for grpcHealthStatus(t, h) != grpc_health_v1.HealthCheckResponse_SERVING {
+		if time.Now().After(deadline) {
+			t.Fatal("Start should refresh gRPC status")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,269 @@
+package kratosx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// 幂等中间件的 error reason
+const (
+	ReasonIdempotencyInProgress = "IDEMPOTENCY_IN_PROGRESS" // 409：原请求仍在处理
+	ReasonIdempotencyKeyReused  = "IDEMPOTENCY_KEY_REUSED"  // 422：同一 key 携带了不同的请求体
+	ReasonIdempotencyFailed     = "IDEMPOTENCY_FAILED"      // 5xx：读取 / 还原缓存的响应失败
+)
+
+// DefaultIdempotencyHeader 默认读取的幂等 key 请求头 / metadata
+const DefaultIdempotencyHeader = "x-idempotency-key"
+
+// idempotencyRunningPrefix 处理中标记的前缀，后接本次请求的随机 token
+const idempotencyRunningPrefix = "running:"
+
+type idempotencyOptions struct {
+	prefix       string
+	header       string
+	ttl          time.Duration
+	lockTTL      time.Duration
+	replyFactory func(operation string) interface{}
+	logger       log.Logger
+}
+
+// IdempotencyOption Idempotency 的可选参数
+type IdempotencyOption func(o *idempotencyOptions)
+
+// WithIdempotencyPrefix Redis key 前缀，默认 idempotency
+func WithIdempotencyPrefix(prefix string) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.prefix = prefix }
+}
+
+// WithIdempotencyHeader 幂等 key 所在的请求头 / metadata，默认 x-idempotency-key
+func WithIdempotencyHeader(header string) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.header = header }
+}
+
+// WithIdempotencyTTL 响应的缓存时间，默认 24h
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.ttl = ttl }
+}
+
+// WithIdempotencyLockTTL 处理中标记的过期时间，应大于 handler 的最长耗时，默认 1m
+func WithIdempotencyLockTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.lockTTL = ttl }
+}
+
+// WithIdempotencyReplyFactory 为非 proto 响应创建空的 reply 实例，用于重放时反序列化；
+// proto 响应通过 protoregistry 按类型名还原，无需设置
+func WithIdempotencyReplyFactory(fn func(operation string) interface{}) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.replyFactory = fn }
+}
+
+// WithIdempotencyLogger 日志输出，默认 log.GetLogger()
+func WithIdempotencyLogger(logger log.Logger) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.logger = logger }
+}
+
+// idempotencyRecord 缓存在 Redis 中的处理结果
+type idempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	Type        string            `json:"type,omitempty"` // proto 响应的 full name
+	Reply       string            `json:"reply,omitempty"`
+	Code        int32             `json:"code,omitempty"`
+	Reason      string            `json:"reason,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Idempotency 基于 Redis 的幂等中间件，请求未携带幂等 key 时直接放行：
+// - 首个请求写入处理中标记（SET NX），完成后以 Codec 缓存响应（含 kratos 错误码 / reason），key 为 prefix:operation:幂等key
+// - 重复请求：已完成则重放缓存的响应；仍在处理则返回 409；请求体不同则返回 422
+// - 仅缓存成功响应与 4xx 错误；5xx / 非 kratos 错误视为可重试，删除标记以便重新执行
+// - 非 proto 响应需设置 WithIdempotencyReplyFactory 才会缓存，否则同样删除标记
+// - Redis 不可用时放行并记录日志
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+func Idempotency(rdb redis.Cmdable, opts ...IdempotencyOption) middleware.Middleware {
+	o := idempotencyOptions{
+		prefix:  "idempotency",
+		header:  DefaultIdempotencyHeader,
+		ttl:     24 * time.Hour,
+		lockTTL: time.Minute,
+		logger:  log.GetLogger(),
+	}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	helper := log.NewHelper(log.With(o.logger, "module", "kratosx/idempotency"))
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			info, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			idemKey := strings.TrimSpace(info.RequestHeader().Get(o.header))
+			if idemKey == "" {
+				return handler(ctx, req)
+			}
+			operation := info.Operation()
+			key := o.prefix + ":" + operation + ":" + idemKey
+			fingerprint := requestFingerprint(req)
+			token := idempotencyRunningPrefix + randToken()
+
+			acquired, err := rdb.SetNX(ctx, key, token, o.lockTTL).Result()
+			if err != nil {
+				helper.WithContext(ctx).Warnf("idempotency check failed, pass through: op=%s err=%v", operation, err)
+				return handler(ctx, req)
+			}
+			if !acquired {
+				return replayIdempotent(ctx, rdb, key, operation, fingerprint, o.replyFactory)
+			}
+
+			reply, err := handler(ctx, req)
+
+			// 请求 ctx 可能已取消，写回结果不受其影响
+			storeCtx := context.WithoutCancel(ctx)
+			rec, cacheable := newIdempotencyRecord(fingerprint, reply, err, o.replyFactory != nil)
+			if cacheable {
+				bs, merr := Codec.Marshal(rec)
+				if merr == nil {
+					merr = rdb.Eval(storeCtx, idempotencyStoreScript, []string{key}, token, bs, o.ttl.Milliseconds()).Err()
+				}
+				if merr == nil {
+					return reply, err
+				}
+				helper.WithContext(ctx).Warnf("store idempotent reply failed: op=%s err=%v", operation, merr)
+			}
+			if derr := rdb.Eval(storeCtx, idempotencyDeleteScript, []string{key}, token).Err(); derr != nil {
+				helper.WithContext(ctx).Warnf("release idempotency marker failed: op=%s err=%v", operation, derr)
+			}
+			return reply, err
+		}
+	}
+}
+
+// idempotencyStoreScript 仅当标记仍属于本次请求时写入结果
+const idempotencyStoreScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return false
+`
+
+// idempotencyDeleteScript 仅当标记仍属于本次请求时删除
+const idempotencyDeleteScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+func replayIdempotent(
+	ctx context.Context,
+	rdb redis.Cmdable,
+	key, operation, fingerprint string,
+	replyFactory func(string) interface{},
+) (interface{}, error) {
+	val, err := rdb.Get(ctx, key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, errors.ServiceUnavailable(ReasonIdempotencyFailed, "idempotency store unavailable").WithCause(err)
+	}
+	// 标记刚好过期或仍在处理，都让调用方稍后重试
+	if err != nil || strings.HasPrefix(val, idempotencyRunningPrefix) {
+		return nil, errors.Conflict(ReasonIdempotencyInProgress, "request with the same idempotency key is in progress")
+	}
+
+	var rec idempotencyRecord
+	if err := Codec.Unmarshal([]byte(val), &rec); err != nil {
+		return nil, errors.InternalServer(ReasonIdempotencyFailed, "decode idempotent reply").WithCause(err)
+	}
+	return rec.replay(operation, fingerprint, replyFactory)
+}
+
+// newIdempotencyRecord 构造缓存记录；返回 false 表示结果不应缓存。
+// 非 proto 响应仅在设置了 replyFactory 时缓存，否则重放时无法还原，重复请求会一直失败到缓存过期
+func newIdempotencyRecord(fingerprint string, reply interface{}, err error, hasFactory bool) (idempotencyRecord, bool) {
+	rec := idempotencyRecord{Fingerprint: fingerprint}
+	if err != nil {
+		se := errors.FromError(err)
+		if se == nil || se.Code < 400 || se.Code >= 500 {
+			return rec, false
+		}
+		rec.Code, rec.Reason, rec.Message, rec.Metadata = se.Code, se.Reason, se.Message, se.Metadata
+		return rec, true
+	}
+	if m, ok := reply.(proto.Message); ok {
+		rec.Type = string(m.ProtoReflect().Descriptor().FullName())
+	} else if !hasFactory {
+		return rec, false
+	}
+	bs, merr := Codec.Marshal(reply)
+	if merr != nil {
+		return rec, false
+	}
+	rec.Reply = string(bs)
+	return rec, true
+}
+
+// replay 还原缓存的响应或错误
+func (r idempotencyRecord) replay(operation, fingerprint string, replyFactory func(string) interface{}) (interface{}, error) {
+	if r.Fingerprint != fingerprint {
+		return nil, errors.New(422, ReasonIdempotencyKeyReused, "idempotency key reused with a different request")
+	}
+	if r.Code != 0 {
+		return nil, errors.New(int(r.Code), r.Reason, r.Message).WithMetadata(r.Metadata)
+	}
+
+	var reply interface{}
+	if r.Type != "" {
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(r.Type))
+		if err != nil {
+			return nil, errors.InternalServer(ReasonIdempotencyFailed, "unknown reply type "+r.Type).WithCause(err)
+		}
+		reply = mt.New().Interface()
+	} else if replyFactory != nil {
+		reply = replyFactory(operation)
+	}
+	if reply == nil {
+		return nil, errors.InternalServer(ReasonIdempotencyFailed, "cannot restore reply for "+operation)
+	}
+	if err := Codec.Unmarshal([]byte(r.Reply), reply); err != nil {
+		return nil, errors.InternalServer(ReasonIdempotencyFailed, "decode idempotent reply").WithCause(err)
+	}
+	return reply, nil
+}
+
+// requestFingerprint 请求体的 sha256，用于识别同一幂等 key 被不同请求复用；
+// proto 使用确定性二进制编码（protojson 输出的空白在不同构建间可能不同）
+func requestFingerprint(req interface{}) string {
+	var (
+		bs  []byte
+		err error
+	)
+	if m, ok := req.(proto.Message); ok {
+		bs, err = proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	} else {
+		bs, err = Codec.Marshal(req)
+	}
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+func randToken() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
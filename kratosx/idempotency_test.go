@@ -0,0 +1,154 @@
+package kratosx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestIdempotencyRecordReplayProto(t *testing.T) {
+	req := wrapperspb.String("create")
+	fp := requestFingerprint(req)
+	if fp == "" || fp != requestFingerprint(wrapperspb.String("create")) {
+		t.Fatal("fingerprint should be stable")
+	}
+
+	rec, ok := newIdempotencyRecord(fp, wrapperspb.Int64(42), nil, false)
+	if !ok || rec.Type != "google.protobuf.Int64Value" {
+		t.Fatalf("rec=%+v ok=%v", rec, ok)
+	}
+	bs, err := Codec.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded idempotencyRecord
+	if err := Codec.Unmarshal(bs, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := decoded.replay("/a.Svc/Create", fp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(reply.(proto.Message), wrapperspb.Int64(42)) {
+		t.Fatalf("reply=%v", reply)
+	}
+
+	_, err = decoded.replay("/a.Svc/Create", requestFingerprint(wrapperspb.String("other")), nil)
+	if se := errors.FromError(err); se.Code != 422 || se.Reason != ReasonIdempotencyKeyReused {
+		t.Fatalf("reused key err=%v", err)
+	}
+}
+
+func TestIdempotencyRecordReplayError(t *testing.T) {
+	orig := errors.BadRequest("INVALID_NAME", "name is empty").WithMetadata(map[string]string{"field": "name"})
+	rec, ok := newIdempotencyRecord("fp", nil, orig, false)
+	if !ok {
+		t.Fatal("4xx error should be cacheable")
+	}
+	_, err := rec.replay("/a.Svc/Create", "fp", nil)
+	se := errors.FromError(err)
+	if se.Code != 400 || se.Reason != "INVALID_NAME" || se.Message != "name is empty" || se.Metadata["field"] != "name" {
+		t.Fatalf("replayed err=%v", se)
+	}
+
+	if _, ok := newIdempotencyRecord("fp", nil, errors.InternalServer("DB", "down"), false); ok {
+		t.Fatal("5xx error should not be cacheable")
+	}
+	if _, ok := newIdempotencyRecord("fp", nil, context.DeadlineExceeded, false); ok {
+		t.Fatal("non-kratos error should not be cacheable")
+	}
+}
+
+func TestIdempotencyRecordReplayFactory(t *testing.T) {
+	type result struct {
+		ID int `json:"id"`
+	}
+	if _, ok := newIdempotencyRecord("fp", &result{ID: 7}, nil, false); ok {
+		t.Fatal("plain struct without factory should not be cacheable")
+	}
+	rec, ok := newIdempotencyRecord("fp", &result{ID: 7}, nil, true)
+	if !ok {
+		t.Fatal("plain struct with factory should be cacheable")
+	}
+	if _, err := rec.replay("/a.Svc/Create", "fp", nil); err == nil {
+		t.Fatal("non-proto reply without factory should fail")
+	}
+	reply, err := rec.replay("/a.Svc/Create", "fp", func(string) interface{} { return &result{} })
+	if err != nil || reply.(*result).ID != 7 {
+		t.Fatalf("reply=%v err=%v", reply, err)
+	}
+}
+
+func TestIdempotencyPassThrough(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	calls := 0
+	next := func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return "ok", nil
+	}
+	h := Idempotency(rdb)(next)
+
+	tr := newTestTransport("/a.Svc/Create")
+	ctx := transport.NewServerContext(context.Background(), tr)
+	if _, err := h(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tr.reqHeader.Set(DefaultIdempotencyHeader, "k1")
+	if _, err := h(ctx, nil); err != nil {
+		t.Fatalf("redis unavailable should pass through: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls=%d", calls)
+	}
+}
+
+func TestIdempotencyNonProtoReply(t *testing.T) {
+	type result struct {
+		ID int `json:"id"`
+	}
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	calls := 0
+	next := func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return &result{ID: calls}, nil
+	}
+	tr := newTestTransport("/a.Svc/Create")
+	tr.reqHeader.Set(DefaultIdempotencyHeader, "k1")
+	ctx := transport.NewServerContext(context.Background(), tr)
+
+	// 未设置 replyFactory：无法还原的响应不缓存，重复请求重新执行而不是返回 500
+	h := Idempotency(rdb)(next)
+	for i := 1; i <= 2; i++ {
+		reply, err := h(ctx, wrapperspb.String("create"))
+		if err != nil || reply.(*result).ID != i {
+			t.Fatalf("call %d reply=%v err=%v", i, reply, err)
+		}
+	}
+	if mr.Exists("idempotency:/a.Svc/Create:k1") {
+		t.Fatal("marker should be released when reply is not restorable")
+	}
+
+	// 设置 replyFactory 后缓存并重放
+	h = Idempotency(rdb, WithIdempotencyReplyFactory(func(string) interface{} { return &result{} }))(next)
+	for i := 0; i < 2; i++ {
+		reply, err := h(ctx, wrapperspb.String("create"))
+		if err != nil || reply.(*result).ID != 3 {
+			t.Fatalf("replay %d reply=%v err=%v", i, reply, err)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("calls=%d", calls)
+	}
+}
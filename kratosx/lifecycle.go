@@ -0,0 +1,299 @@
+package kratosx
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+)
+
+// Phase 关闭阶段，按声明顺序依次执行
+type Phase int
+
+const (
+	PhaseDeregister Phase = iota // 从注册中心注销（nacosx.Registry 等）
+	PhaseHealth                  // 健康检查切换为 NOT_SERVING
+	PhaseDrain                   // 等待负载均衡摘流，之后等待 drain 时长
+	PhaseServers                 // 停止 gRPC / HTTP 服务
+	PhaseWorkers                 // 停止后台任务（RedisLeader、Streams 消费者等）
+	PhaseResources               // 关闭 ConnFactory、数据库连接池、Redis 客户端等
+	PhaseLogs                    // 刷新并关闭日志；本阶段日志在执行钩子前输出，失败时写到 stderr
+
+	phaseCount = iota
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseDeregister:
+		return "deregister"
+	case PhaseHealth:
+		return "health"
+	case PhaseDrain:
+		return "drain"
+	case PhaseServers:
+		return "servers"
+	case PhaseWorkers:
+		return "workers"
+	case PhaseResources:
+		return "resources"
+	case PhaseLogs:
+		return "logs"
+	default:
+		return fmt.Sprintf("Phase(%d)", int(p))
+	}
+}
+
+type lifecycleOptions struct {
+	drain    time.Duration
+	timeout  time.Duration
+	timeouts map[Phase]time.Duration
+}
+
+// LifecycleOption NewLifecycle 的可选参数
+type LifecycleOption func(o *lifecycleOptions)
+
+// WithDrainPeriod PhaseDrain 的等待时长，默认 5s
+func WithDrainPeriod(d time.Duration) LifecycleOption {
+	return func(o *lifecycleOptions) { o.drain = d }
+}
+
+// WithPhaseTimeout 覆盖单个阶段的超时，未设置的阶段使用默认 10s（PhaseDrain 另加 drain 时长）
+func WithPhaseTimeout(p Phase, d time.Duration) LifecycleOption {
+	return func(o *lifecycleOptions) { o.timeouts[p] = d }
+}
+
+type lifecycleHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Lifecycle 按阶段有序关闭：注销 → 健康检查 NOT_SERVING → 摘流等待 → 停止服务 → 停止后台任务 → 关闭资源 → 关闭日志。
+// 同一阶段内的钩子并发执行，每个阶段单独超时并输出一行日志。
+// wire provider 可注入 *Lifecycle 并用 Append / AppendCleanup 登记关闭逻辑（不再把 cleanup 交给 wire）；
+// 与 kratos.App 配合时使用 kratos.BeforeStop(lc.Shutdown)，并通过 Registrar / Server 包装注册中心与服务，
+// 使 App 后续的注销与 Stop 成为空操作
+type Lifecycle struct {
+	opts lifecycleOptions
+	log  *log.Helper
+
+	mu    sync.Mutex
+	hooks [phaseCount][]lifecycleHook
+
+	once sync.Once
+	err  error
+}
+
+// NewLifecycle 创建关闭协调器
+func NewLifecycle(logger log.Logger, opts ...LifecycleOption) *Lifecycle {
+	o := lifecycleOptions{
+		drain:    5 * time.Second,
+		timeout:  10 * time.Second,
+		timeouts: make(map[Phase]time.Duration),
+	}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	return &Lifecycle{
+		opts: o,
+		log:  log.NewHelper(log.With(logger, "module", "kratosx/lifecycle")),
+	}
+}
+
+// Append 在 phase 登记关闭钩子，如 Append(PhaseWorkers, "leader", leader.Stop)
+func (l *Lifecycle) Append(phase Phase, name string, fn func(ctx context.Context) error) {
+	if phase < 0 || phase >= phaseCount {
+		panic(fmt.Sprintf("kratosx: invalid lifecycle phase %d", phase))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks[phase] = append(l.hooks[phase], lifecycleHook{name: name, fn: fn})
+}
+
+// AppendCleanup 登记无参数的 cleanup 函数，如 friendly.NewRedis、NewConnFactory、logx.New 返回的关闭函数；
+// cleanup 不感知超时，超时后该阶段不再等待其返回
+func (l *Lifecycle) AppendCleanup(phase Phase, name string, cleanup func()) {
+	l.Append(phase, name, func(context.Context) error {
+		cleanup()
+		return nil
+	})
+}
+
+// AddHealth 在 PhaseHealth 将 h 切换为 NOT_SERVING
+func (l *Lifecycle) AddHealth(h *Health) {
+	l.Append(PhaseHealth, "health", h.Shutdown)
+}
+
+// Registrar 包装注册中心：Register 时记录实例，在 PhaseDeregister 注销；
+// 之后 kratos.App 再调用 Deregister 时直接返回
+func (l *Lifecycle) Registrar(r registry.Registrar) registry.Registrar {
+	lr := &lifecycleRegistrar{Registrar: r}
+	l.Append(PhaseDeregister, "registry", lr.deregisterAll)
+	return lr
+}
+
+// Server 包装服务：在 PhaseServers 停止；之后 kratos.App 再调用 Stop 时直接返回
+func (l *Lifecycle) Server(s transport.Server) transport.Server {
+	ls := &lifecycleServer{Server: s}
+	l.Append(PhaseServers, fmt.Sprintf("%T", s), ls.stop)
+	if e, ok := s.(transport.Endpointer); ok {
+		return &lifecycleEndpointServer{lifecycleServer: ls, endpointer: e}
+	}
+	return ls
+}
+
+// Shutdown 依次执行各阶段，只执行一次；返回各阶段错误的合并
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.once.Do(func() {
+		var errs []error
+		for p := Phase(0); p < phaseCount; p++ {
+			if err := l.runPhase(ctx, p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		l.err = stderrors.Join(errs...)
+	})
+	return l.err
+}
+
+func (l *Lifecycle) runPhase(ctx context.Context, p Phase) error {
+	l.mu.Lock()
+	hooks := l.hooks[p]
+	l.mu.Unlock()
+
+	timeout, ok := l.opts.timeouts[p]
+	if !ok {
+		timeout = l.opts.timeout
+		if p == PhaseDrain {
+			timeout += l.opts.drain
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	if p == PhaseLogs {
+		// 该阶段的钩子会刷新并关闭日志，之后 l.log 不再可用，提前输出本阶段日志
+		l.log.Infof("shutdown phase %s start, hooks=%d", p, len(hooks))
+	}
+	errs := make([]error, len(hooks))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i, h := range hooks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := h.fn(ctx); err != nil {
+					errs[i] = errors.WithMessagef(err, "%s/%s", p, h.name)
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	var err error
+	select {
+	case <-done:
+		err = stderrors.Join(errs...)
+	case <-ctx.Done():
+		err = errors.Wrapf(ctx.Err(), "phase %s", p)
+	}
+	if p == PhaseDrain && err == nil && l.opts.drain > 0 {
+		t := time.NewTimer(l.opts.drain)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			err = errors.Wrapf(ctx.Err(), "phase %s", p)
+		}
+	}
+
+	switch {
+	case p == PhaseLogs:
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "shutdown phase %s failed, hooks=%d cost=%s: %v\n", p, len(hooks), time.Since(start), err)
+		}
+	case err != nil:
+		l.log.Errorf("shutdown phase %s failed, hooks=%d cost=%s: %v", p, len(hooks), time.Since(start), err)
+	default:
+		l.log.Infof("shutdown phase %s done, hooks=%d cost=%s", p, len(hooks), time.Since(start))
+	}
+	return err
+}
+
+// lifecycleRegistrar 记录已注册的实例，注销只执行一次
+type lifecycleRegistrar struct {
+	registry.Registrar
+
+	mu           sync.Mutex
+	instances    []*registry.ServiceInstance
+	deregistered bool
+}
+
+func (r *lifecycleRegistrar) Register(ctx context.Context, ins *registry.ServiceInstance) error {
+	if err := r.Registrar.Register(ctx, ins); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.instances = append(r.instances, ins)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *lifecycleRegistrar) Deregister(ctx context.Context, ins *registry.ServiceInstance) error {
+	r.mu.Lock()
+	done := r.deregistered
+	r.mu.Unlock()
+	if done {
+		return nil
+	}
+	return r.Registrar.Deregister(ctx, ins)
+}
+
+func (r *lifecycleRegistrar) deregisterAll(ctx context.Context) error {
+	r.mu.Lock()
+	instances := r.instances
+	r.deregistered = true
+	r.mu.Unlock()
+
+	var errs []error
+	for _, ins := range instances {
+		if err := r.Registrar.Deregister(ctx, ins); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "deregister %s", ins.ID))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// lifecycleServer Stop 只执行一次，重复调用返回 nil
+type lifecycleServer struct {
+	transport.Server
+	once sync.Once
+}
+
+func (s *lifecycleServer) stop(ctx context.Context) (err error) {
+	s.once.Do(func() { err = s.Server.Stop(ctx) })
+	return err
+}
+
+func (s *lifecycleServer) Stop(ctx context.Context) error {
+	return s.stop(ctx)
+}
+
+type lifecycleEndpointServer struct {
+	*lifecycleServer
+	endpointer transport.Endpointer
+}
+
+func (s *lifecycleEndpointServer) Endpoint() (*url.URL, error) {
+	return s.endpointer.Endpoint()
+}
@@ -0,0 +1,152 @@
+package kratosx
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+)
+
+func TestLifecycleOrder(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	lc := NewLifecycle(log.DefaultLogger, WithDrainPeriod(0))
+	lc.Append(PhaseLogs, "logs", record("logs"))
+	lc.AppendCleanup(PhaseResources, "db", func() { _ = record("db")(context.Background()) })
+	lc.Append(PhaseWorkers, "leader", record("leader"))
+	lc.Append(PhaseServers, "grpc", record("grpc"))
+	lc.Append(PhaseHealth, "health", record("health"))
+	lc.Append(PhaseDeregister, "registry", record("registry"))
+
+	if err := lc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ","); got != "registry,health,grpc,leader,db,logs" {
+		t.Fatalf("order=%s", got)
+	}
+	if err := lc.Shutdown(context.Background()); err != nil || len(order) != 6 {
+		t.Fatal("shutdown should run once")
+	}
+}
+
+func TestLifecyclePhaseTimeoutAndErrors(t *testing.T) {
+	lc := NewLifecycle(log.DefaultLogger, WithDrainPeriod(0), WithPhaseTimeout(PhaseWorkers, 20*time.Millisecond))
+	lc.Append(PhaseWorkers, "stuck", func(ctx context.Context) error {
+		select {} // 不响应 ctx 的钩子不能阻塞后续阶段
+	})
+	lc.Append(PhaseResources, "broken", func(context.Context) error { return errors.New("close failed") })
+	logsClosed := false
+	lc.AppendCleanup(PhaseLogs, "logs", func() { logsClosed = true })
+
+	err := lc.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "phase workers") || !strings.Contains(err.Error(), "resources/broken: close failed") {
+		t.Fatalf("err=%v", err)
+	}
+	if !logsClosed {
+		t.Fatal("later phases should still run")
+	}
+}
+
+func TestLifecycleLogsPhaseLogsBeforeClose(t *testing.T) {
+	logger := &recordLogger{}
+	lc := NewLifecycle(logger, WithDrainPeriod(0))
+	var linesAtClose int
+	lc.AppendCleanup(PhaseLogs, "logs", func() {
+		logger.mu.Lock()
+		defer logger.mu.Unlock()
+		linesAtClose = len(logger.lines)
+	})
+	if err := lc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if linesAtClose != len(logger.lines) {
+		t.Fatalf("logged after logs phase closed the logger: %v", logger.lines[linesAtClose:])
+	}
+	if last := logger.lines[len(logger.lines)-1]; !strings.Contains(last, "shutdown phase logs start") {
+		t.Fatalf("last line=%q", last)
+	}
+}
+
+func TestLifecycleDrain(t *testing.T) {
+	lc := NewLifecycle(log.DefaultLogger, WithDrainPeriod(50*time.Millisecond))
+	start := time.Now()
+	if err := lc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cost := time.Since(start); cost < 50*time.Millisecond {
+		t.Fatalf("drain period not waited, cost=%s", cost)
+	}
+}
+
+type countingRegistrar struct {
+	mu           sync.Mutex
+	registered   int
+	deregistered int
+}
+
+func (r *countingRegistrar) Register(context.Context, *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registered++
+	return nil
+}
+
+func (r *countingRegistrar) Deregister(context.Context, *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deregistered++
+	return nil
+}
+
+type countingServer struct{ stops int }
+
+func (s *countingServer) Start(context.Context) error { return nil }
+func (s *countingServer) Stop(context.Context) error  { s.stops++; return nil }
+func (s *countingServer) Endpoint() (*url.URL, error) { return url.Parse("grpc://127.0.0.1:9000") }
+
+func TestLifecycleWrappers(t *testing.T) {
+	lc := NewLifecycle(log.DefaultLogger, WithDrainPeriod(0))
+	reg := &countingRegistrar{}
+	srv := &countingServer{}
+	wrappedReg := lc.Registrar(reg)
+	wrappedSrv := lc.Server(srv)
+
+	if _, ok := wrappedSrv.(transport.Endpointer); !ok {
+		t.Fatal("wrapper should keep Endpointer")
+	}
+	ins := &registry.ServiceInstance{ID: "1", Name: "order"}
+	if err := wrappedReg.Register(context.Background(), ins); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// kratos.App 之后的注销与 Stop 为空操作
+	_ = wrappedReg.Deregister(context.Background(), ins)
+	_ = wrappedSrv.Stop(context.Background())
+	if reg.deregistered != 1 || srv.stops != 1 {
+		t.Fatalf("deregistered=%d stops=%d", reg.deregistered, srv.stops)
+	}
+}
@@ -0,0 +1,228 @@
+package kratosx
+
+import (
+	"math/rand/v2"
+	"path"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DefaultMaxBodyLen 请求 / 响应体日志的默认截断长度
+const DefaultMaxBodyLen = 1024
+
+// redactedValue 脱敏后的占位值
+const redactedValue = "***"
+
+// LogBodyLimit 按 operation 设置请求 / 响应体的截断长度
+type LogBodyLimit struct {
+	Operation string // path.Match 语法，如 /pkg.Service/*
+	MaxLen    int    // <0 时不记录请求 / 响应体
+}
+
+// LogPolicy ServerLogger / ClientLogger 的记录策略，零值即默认行为（全部记录，截断到 DefaultMaxBodyLen）
+type LogPolicy struct {
+	// MaxBodyLen 请求 / 响应体截断长度，0 为 DefaultMaxBodyLen，<0 时不记录请求 / 响应体
+	MaxBodyLen int
+	// BodyLimits 按 operation 覆盖 MaxBodyLen，按顺序取第一个匹配项
+	BodyLimits []LogBodyLimit
+	// Skip 完全不记录的 operation（path.Match 语法），如 /grpc.health.v1.Health/*
+	Skip []string
+	// SampleRate 成功请求的采样率，(0, 1) 之间生效，其余值全部记录；失败请求始终记录
+	SampleRate float64
+	// RedactFields 需要脱敏的字段：不含 . 时按字段名在任意层级匹配（proto 字段名或 JSON 名），
+	// 含 . 时按自根起的完整路径匹配（如 user.password）
+	RedactFields []string
+	// RedactOption 字段上带有该 bool 类型 proto 扩展选项且值为 true 时脱敏，
+	// 如 string password = 1 [(myapp.sensitive) = true]
+	RedactOption protoreflect.ExtensionType
+}
+
+// LoggerOption ServerLogger / ClientLogger 的可选参数
+type LoggerOption func(p *LogPolicy)
+
+// WithLogPolicy 使用给定的记录策略
+func WithLogPolicy(policy LogPolicy) LoggerOption {
+	return func(p *LogPolicy) { *p = policy }
+}
+
+func newLogPolicy(opts ...LoggerOption) *logPolicy {
+	var p LogPolicy
+	for _, fn := range opts {
+		fn(&p)
+	}
+	return p.compile()
+}
+
+// logPolicy 预处理后的 LogPolicy
+type logPolicy struct {
+	LogPolicy
+	names map[string]bool // 任意层级匹配的字段名
+	paths map[string]bool // 完整路径
+}
+
+func (p LogPolicy) compile() *logPolicy {
+	c := &logPolicy{LogPolicy: p, names: map[string]bool{}, paths: map[string]bool{}}
+	if c.MaxBodyLen == 0 {
+		c.MaxBodyLen = DefaultMaxBodyLen
+	}
+	for _, f := range p.RedactFields {
+		if strings.Contains(f, ".") {
+			c.paths[f] = true
+		} else {
+			c.names[f] = true
+		}
+	}
+	return c
+}
+
+// skip operation 是否完全不记录
+func (p *logPolicy) skip(operation string) bool {
+	for _, g := range p.Skip {
+		if ok, _ := path.Match(g, operation); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sampled 成功请求是否记录
+func (p *logPolicy) sampled() bool {
+	if p.SampleRate <= 0 || p.SampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < p.SampleRate //nolint:gosec
+}
+
+func (p *logPolicy) bodyLen(operation string) int {
+	for _, l := range p.BodyLimits {
+		if ok, _ := path.Match(l.Operation, operation); ok {
+			return l.MaxLen
+		}
+	}
+	return p.MaxBodyLen
+}
+
+// body 脱敏并截断后的请求 / 响应体
+func (p *logPolicy) body(operation string, v interface{}) string {
+	maxLen := p.bodyLen(operation)
+	if maxLen < 0 {
+		return ""
+	}
+	return TruncateBytes(extractArgs(p.redact(v)), maxLen)
+}
+
+func (p *logPolicy) redactEnabled() bool {
+	return len(p.names) > 0 || len(p.paths) > 0 || p.RedactOption != nil
+}
+
+// redact 返回脱敏后的副本；proto 按反射处理（支持扩展选项），其他类型按 JSON 处理
+func (p *logPolicy) redact(v interface{}) interface{} {
+	if v == nil || !p.redactEnabled() {
+		return v
+	}
+	if m, ok := v.(proto.Message); ok {
+		if !m.ProtoReflect().IsValid() {
+			return v
+		}
+		c := proto.Clone(m)
+		p.redactProto(c.ProtoReflect(), "", "")
+		return c
+	}
+	if len(p.names) == 0 && len(p.paths) == 0 {
+		return v
+	}
+	bs, err := sonic.ConfigFastest.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var generic interface{}
+	if err := sonic.ConfigFastest.Unmarshal(bs, &generic); err != nil {
+		return v
+	}
+	return p.redactJSON(generic, "")
+}
+
+func (p *logPolicy) match(name, jsonName, fullPath, jsonPath string) bool {
+	return p.names[name] || p.names[jsonName] || p.paths[fullPath] || p.paths[jsonPath]
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func (p *logPolicy) sensitive(fd protoreflect.FieldDescriptor) bool {
+	if p.RedactOption == nil || fd.Options() == nil {
+		return false
+	}
+	opts, ok := fd.Options().(proto.Message)
+	if !ok || !proto.HasExtension(opts, p.RedactOption) {
+		return false
+	}
+	b, _ := proto.GetExtension(opts, p.RedactOption).(bool)
+	return b
+}
+
+// redactProto 原地脱敏，路径同时按 proto 字段名与 JSON 名匹配
+func (p *logPolicy) redactProto(m protoreflect.Message, prefix, jsonPrefix string) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := string(fd.Name())
+		fullPath, jsonPath := joinPath(prefix, name), joinPath(jsonPrefix, fd.JSONName())
+
+		if p.match(name, fd.JSONName(), fullPath, jsonPath) || p.sensitive(fd) {
+			if !fd.IsList() && !fd.IsMap() && (fd.Kind() == protoreflect.StringKind || fd.Kind() == protoreflect.BytesKind) {
+				if fd.Kind() == protoreflect.StringKind {
+					m.Set(fd, protoreflect.ValueOfString(redactedValue))
+				} else {
+					m.Set(fd, protoreflect.ValueOfBytes([]byte(redactedValue)))
+				}
+			} else {
+				m.Clear(fd) // 非字符串字段无法写入占位值，直接清空
+			}
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				p.redactProto(l.Get(i).Message(), fullPath, jsonPath)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				p.redactProto(mv.Message(), fullPath, jsonPath)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			p.redactProto(v.Message(), fullPath, jsonPath)
+		}
+		return true
+	})
+}
+
+func (p *logPolicy) redactJSON(v interface{}, prefix string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			fullPath := joinPath(prefix, k)
+			if p.match(k, k, fullPath, fullPath) {
+				t[k] = redactedValue
+				continue
+			}
+			t[k] = p.redactJSON(child, fullPath)
+		}
+		return t
+	case []interface{}:
+		for i := range t {
+			t[i] = p.redactJSON(t[i], prefix)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -0,0 +1,189 @@
+package kratosx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestLogPolicySkipAndBodyLen(t *testing.T) {
+	p := LogPolicy{
+		Skip:       []string{"/grpc.health.v1.Health/*"},
+		MaxBodyLen: 16,
+		BodyLimits: []LogBodyLimit{
+			{Operation: "/file.Svc/Upload", MaxLen: -1},
+			{Operation: "/file.Svc/*", MaxLen: 4},
+		},
+	}.compile()
+
+	if !p.skip("/grpc.health.v1.Health/Check") || p.skip("/order.Svc/Get") {
+		t.Fatal("unexpected skip result")
+	}
+	if got := p.body("/file.Svc/Upload", "abcdefgh"); got != "" {
+		t.Fatalf("omitted body=%q", got)
+	}
+	if got := p.body("/file.Svc/Download", map[string]string{"k": "v"}); !strings.HasPrefix(got, `{"k"...`) {
+		t.Fatalf("per-operation limit body=%q", got)
+	}
+	if got := p.body("/order.Svc/Get", map[string]string{"k": "v"}); got != `{"k":"v"}` {
+		t.Fatalf("default limit body=%q", got)
+	}
+	if got := (LogPolicy{}).compile().MaxBodyLen; got != DefaultMaxBodyLen {
+		t.Fatalf("zero policy max len=%d", got)
+	}
+}
+
+func TestLogPolicySampled(t *testing.T) {
+	for _, rate := range []float64{0, 1, -1, 2} {
+		if !(LogPolicy{SampleRate: rate}).compile().sampled() {
+			t.Fatalf("rate %v should log all", rate)
+		}
+	}
+	p := LogPolicy{SampleRate: 0.001}.compile()
+	n := 0
+	for i := 0; i < 1000; i++ {
+		if p.sampled() {
+			n++
+		}
+	}
+	if n > 50 {
+		t.Fatalf("sampled %d of 1000 at rate 0.001", n)
+	}
+}
+
+func TestLogPolicyRedactJSON(t *testing.T) {
+	p := LogPolicy{RedactFields: []string{"password", "user.token"}}.compile()
+	in := map[string]interface{}{
+		"password": "p1",
+		"token":    "keep",
+		"user":     map[string]interface{}{"token": "t1", "name": "bob"},
+		"items":    []interface{}{map[string]interface{}{"password": "p2"}},
+	}
+	got := p.body("", in)
+	for _, leaked := range []string{"p1", "p2", "t1"} {
+		if strings.Contains(got, leaked) {
+			t.Fatalf("%q leaked in %s", leaked, got)
+		}
+	}
+	if !strings.Contains(got, "keep") || !strings.Contains(got, "bob") {
+		t.Fatalf("unexpected redaction: %s", got)
+	}
+	if in["password"] != "p1" {
+		t.Fatal("input should not be modified")
+	}
+}
+
+func TestLogPolicyRedactProto(t *testing.T) {
+	p := LogPolicy{RedactFields: []string{"json_name", "options.javaPackage"}}.compile()
+	in := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("a.proto"),
+		Options: &descriptorpb.FileOptions{JavaPackage: proto.String("com.secret")},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("id"), JsonName: proto.String("hidden")}},
+		}},
+	}
+	got := p.body("", in)
+	if strings.Contains(got, "com.secret") || strings.Contains(got, "hidden") {
+		t.Fatalf("field leaked: %s", got)
+	}
+	if !strings.Contains(got, "a.proto") || !strings.Contains(got, "User") {
+		t.Fatalf("unexpected redaction: %s", got)
+	}
+	if in.GetOptions().GetJavaPackage() != "com.secret" {
+		t.Fatal("input should not be modified")
+	}
+}
+
+// sensitiveExtension 动态构造 extend google.protobuf.FieldOptions { bool sensitive = 50001; }
+// 以及带有 [(test.sensitive) = true] 选项的 test.Login 消息
+func sensitiveExtension(t *testing.T) (protoreflect.ExtensionType, protoreflect.MessageDescriptor) {
+	t.Helper()
+	extFile := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test_ext.proto"),
+		Package:    proto.String("test"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{{
+			Name:     proto.String("sensitive"),
+			Number:   proto.Int32(50001),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+			Extendee: proto.String(".google.protobuf.FieldOptions"),
+		}},
+	}
+	fd, err := protodesc.NewFile(extFile, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xt := dynamicpb.NewExtensionType(fd.Extensions().Get(0))
+
+	opts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(opts, xt, true)
+	msgFile := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test_msg.proto"),
+		Package: proto.String("test"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Login"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("user"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("secret"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Options: opts},
+			},
+		}},
+	}
+	md, err := protodesc.NewFile(msgFile, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xt, md.Messages().Get(0)
+}
+
+func TestLogPolicyRedactOption(t *testing.T) {
+	xt, md := sensitiveExtension(t)
+	msg := dynamicpb.NewMessage(md)
+	msg.Set(md.Fields().ByName("user"), protoreflect.ValueOfString("bob"))
+	msg.Set(md.Fields().ByName("secret"), protoreflect.ValueOfString("s3cr3t"))
+
+	redacted := LogPolicy{RedactOption: xt}.compile().redact(msg).(proto.Message).ProtoReflect()
+	if got := redacted.Get(md.Fields().ByName("secret")).String(); got != redactedValue {
+		t.Fatalf("secret=%q", got)
+	}
+	if got := redacted.Get(md.Fields().ByName("user")).String(); got != "bob" {
+		t.Fatalf("user=%q", got)
+	}
+}
+
+func TestServerLoggerPolicy(t *testing.T) {
+	tr := newTestTransport("/grpc.health.v1.Health/Check")
+	ctx := transport.NewServerContext(context.Background(), tr)
+	ok := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	fail := func(context.Context, interface{}) (interface{}, error) {
+		return nil, errors.BadRequest("BAD", "bad")
+	}
+
+	logger := &recordLogger{}
+	_, _ = ServerLogger(logger, WithLogPolicy(LogPolicy{Skip: []string{"/grpc.health.v1.Health/*"}}))(ok)(ctx, nil)
+	if len(logger.lines) != 0 {
+		t.Fatalf("skipped operation logged: %v", logger.lines)
+	}
+
+	tr.operation = "/order.Svc/Create"
+	logger = &recordLogger{}
+	policy := WithLogPolicy(LogPolicy{SampleRate: 1e-9, RedactFields: []string{"password"}})
+	_, _ = ServerLogger(logger, policy)(ok)(ctx, map[string]string{"password": "p"})
+	if len(logger.lines) != 0 {
+		t.Fatalf("unsampled success logged: %v", logger.lines)
+	}
+	_, _ = ServerLogger(logger, policy)(fail)(ctx, map[string]string{"password": "p"})
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "args") || strings.Contains(logger.lines[0], `"p"`) {
+		t.Fatalf("unsampled error should log once with redacted args: %v", logger.lines)
+	}
+}
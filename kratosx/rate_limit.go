@@ -0,0 +1,252 @@
+package kratosx
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/peer"
+)
+
+// 限流中间件的 error reason
+const (
+	ReasonRateLimited            = "RATE_LIMITED"             // 429：超出限流
+	ReasonRateLimiterUnavailable = "RATE_LIMITER_UNAVAILABLE" // 503：关闭放行时 Redis 不可用
+)
+
+// RateLimitKeyFunc 从请求上下文中提取调用方标识；返回空串时该操作的所有调用方共享一个桶
+type RateLimitKeyFunc func(ctx context.Context) string
+
+// RateLimitRule 单条限流规则（令牌桶）：每 Period 补充 Limit 个令牌，桶容量为 Burst
+type RateLimitRule struct {
+	// Operation 精确匹配 transport 的 operation（如 /pkg.Service/Method）；
+	// 以 * 结尾时按前缀匹配（如 /pkg.Service/*）；空串为默认规则
+	Operation string
+	Limit     int64
+	Period    time.Duration
+	Burst     int64            // <=0 时等于 Limit
+	KeyFunc   RateLimitKeyFunc // nil 时使用 WithRateLimitKeyFunc 指定的全局 KeyFunc
+}
+
+type rateLimitOptions struct {
+	prefix   string
+	keyFunc  RateLimitKeyFunc
+	failOpen bool
+	logger   log.Logger
+}
+
+// RateLimitOption RateLimiter 的可选参数
+type RateLimitOption func(o *rateLimitOptions)
+
+// WithRateLimitPrefix Redis key 前缀，默认 ratelimit
+func WithRateLimitPrefix(prefix string) RateLimitOption {
+	return func(o *rateLimitOptions) { o.prefix = prefix }
+}
+
+// WithRateLimitKeyFunc 全局调用方标识提取方式，默认 RateLimitByPeer
+func WithRateLimitKeyFunc(fn RateLimitKeyFunc) RateLimitOption {
+	return func(o *rateLimitOptions) { o.keyFunc = fn }
+}
+
+// WithRateLimitFailOpen Redis 不可用时是否放行，默认放行（仅记录日志）
+func WithRateLimitFailOpen(failOpen bool) RateLimitOption {
+	return func(o *rateLimitOptions) { o.failOpen = failOpen }
+}
+
+// WithRateLimitLogger 日志输出，默认 log.GetLogger()
+func WithRateLimitLogger(logger log.Logger) RateLimitOption {
+	return func(o *rateLimitOptions) { o.logger = logger }
+}
+
+// RateLimitByPeer 以客户端 IP 作为调用方标识：gRPC 取 peer，HTTP 取 RemoteAddr
+func RateLimitByPeer() RateLimitKeyFunc {
+	return func(ctx context.Context) string {
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			addr = p.Addr.String()
+		} else if req, ok := khttp.RequestFromServerContext(ctx); ok {
+			addr = req.RemoteAddr
+		}
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			return host
+		}
+		return addr
+	}
+}
+
+// RateLimitByHeader 以请求头 / metadata 中的字段作为调用方标识（如 x-api-key、x-user-id）
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(ctx context.Context) string {
+		if info, ok := transport.FromServerContext(ctx); ok {
+			return info.RequestHeader().Get(name)
+		}
+		return ""
+	}
+}
+
+// tokenBucketScript 原子地补充并扣减令牌，时间取 Redis 服务端时钟，避免各实例时钟偏差；
+// 返回 {是否放行, 需等待的毫秒数}
+var tokenBucketScript = redis.NewScript(`
+if redis.replicate_commands then redis.replicate_commands() end
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) * rate)
+end
+local allowed, wait = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, wait}
+`)
+
+// RateLimiter 基于 Redis 的集群级限流中间件，按 (operation, 调用方) 维护令牌桶：
+// - 规则按 operation 精确匹配优先，其次最长前缀匹配，最后默认规则；未命中任何规则时不限流
+// - 拒绝时返回 429 / RATE_LIMITED，metadata 与响应头 Retry-After 中带有建议重试时间
+// - Redis 不可用时默认放行；WithRateLimitFailOpen(false) 时返回 503 / RATE_LIMITER_UNAVAILABLE
+// 兼容：*redis.Client / *redis.ClusterClient（通过 redis.Cmdable）
+func RateLimiter(rdb redis.Cmdable, rules []RateLimitRule, opts ...RateLimitOption) middleware.Middleware {
+	o := rateLimitOptions{
+		prefix:   "ratelimit",
+		keyFunc:  RateLimitByPeer(),
+		failOpen: true,
+		logger:   log.GetLogger(),
+	}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	helper := log.NewHelper(log.With(o.logger, "module", "kratosx/ratelimit"))
+	match := newRateLimitMatcher(rules)
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			info, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			operation := info.Operation()
+			rule, ok := match(operation)
+			if !ok {
+				return handler(ctx, req)
+			}
+
+			keyFunc := rule.KeyFunc
+			if keyFunc == nil {
+				keyFunc = o.keyFunc
+			}
+			key := o.prefix + ":" + operation
+			if caller := keyFunc(ctx); caller != "" {
+				key += ":" + caller
+			}
+
+			allowed, retryAfter, err := takeToken(ctx, rdb, key, rule)
+			if err != nil {
+				if o.failOpen {
+					helper.WithContext(ctx).Warnf("rate limit check failed, allow request: op=%s err=%v", operation, err)
+					return handler(ctx, req)
+				}
+				return nil, errors.ServiceUnavailable(ReasonRateLimiterUnavailable, "rate limiter unavailable").WithCause(err)
+			}
+			if !allowed {
+				return nil, rateLimitedError(info, retryAfter)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+func takeToken(ctx context.Context, rdb redis.Cmdable, key string, rule RateLimitRule) (bool, time.Duration, error) {
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.Limit
+	}
+	// 每毫秒补充的令牌数
+	rate := float64(rule.Limit) / float64(rule.Period.Milliseconds())
+
+	res, err := tokenBucketScript.Run(ctx, rdb, []string{key},
+		strconv.FormatFloat(rate, 'g', -1, 64), burst,
+	).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, errors.InternalServer(ReasonRateLimiterUnavailable, "unexpected rate limit script reply")
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// rateLimitedError 构造 429 错误，并在响应头中写入 Retry-After（秒，向上取整）
+func rateLimitedError(info transport.Transporter, retryAfter time.Duration) error {
+	secs := int64((retryAfter + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	info.ReplyHeader().Set("Retry-After", strconv.FormatInt(secs, 10))
+	return errors.New(429, ReasonRateLimited, "too many requests").WithMetadata(map[string]string{
+		"retry_after_ms": strconv.FormatInt(retryAfter.Milliseconds(), 10),
+	})
+}
+
+// newRateLimitMatcher 预处理规则，忽略 Limit / Period 非法的规则
+func newRateLimitMatcher(rules []RateLimitRule) func(operation string) (RateLimitRule, bool) {
+	var (
+		exact    = make(map[string]RateLimitRule)
+		prefixes []RateLimitRule
+		def      *RateLimitRule
+	)
+	for i := range rules {
+		r := rules[i]
+		if r.Limit <= 0 || r.Period < time.Millisecond {
+			continue
+		}
+		switch {
+		case r.Operation == "":
+			def = &r
+		case strings.HasSuffix(r.Operation, "*"):
+			r.Operation = strings.TrimSuffix(r.Operation, "*")
+			prefixes = append(prefixes, r)
+		default:
+			exact[r.Operation] = r
+		}
+	}
+
+	return func(operation string) (RateLimitRule, bool) {
+		if r, ok := exact[operation]; ok {
+			return r, true
+		}
+		var (
+			best  RateLimitRule
+			found bool
+		)
+		for _, r := range prefixes {
+			if strings.HasPrefix(operation, r.Operation) && (!found || len(r.Operation) > len(best.Operation)) {
+				best, found = r, true
+			}
+		}
+		if found {
+			return best, true
+		}
+		if def != nil {
+			return *def, true
+		}
+		return RateLimitRule{}, false
+	}
+}
@@ -0,0 +1,126 @@
+package kratosx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/peer"
+)
+
+type testHeader http.Header
+
+func (h testHeader) Get(key string) string      { return http.Header(h).Get(key) }
+func (h testHeader) Set(key, value string)      { http.Header(h).Set(key, value) }
+func (h testHeader) Add(key, value string)      { http.Header(h).Add(key, value) }
+func (h testHeader) Values(key string) []string { return http.Header(h).Values(key) }
+func (h testHeader) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type testTransport struct {
+	endpoint  string
+	operation string
+	reqHeader testHeader
+	repHeader testHeader
+}
+
+func newTestTransport(operation string) *testTransport {
+	return &testTransport{operation: operation, reqHeader: testHeader{}, repHeader: testHeader{}}
+}
+
+func (t *testTransport) Kind() transport.Kind            { return transport.KindGRPC }
+func (t *testTransport) Endpoint() string                { return t.endpoint }
+func (t *testTransport) Operation() string               { return t.operation }
+func (t *testTransport) RequestHeader() transport.Header { return t.reqHeader }
+func (t *testTransport) ReplyHeader() transport.Header   { return t.repHeader }
+
+func TestRateLimitMatcher(t *testing.T) {
+	match := newRateLimitMatcher([]RateLimitRule{
+		{Operation: "", Limit: 1, Period: time.Second},
+		{Operation: "/a.Svc/*", Limit: 2, Period: time.Second},
+		{Operation: "/a.Svc/Get*", Limit: 3, Period: time.Second},
+		{Operation: "/a.Svc/GetOne", Limit: 4, Period: time.Second},
+		{Operation: "/b.Svc/Bad", Limit: 0, Period: time.Second},
+	})
+	cases := map[string]int64{
+		"/a.Svc/GetOne":  4,
+		"/a.Svc/GetMany": 3,
+		"/a.Svc/Put":     2,
+		"/c.Svc/Any":     1,
+		"/b.Svc/Bad":     1,
+	}
+	for op, want := range cases {
+		r, ok := match(op)
+		if !ok || r.Limit != want {
+			t.Fatalf("match(%q)=%d ok=%v want=%d", op, r.Limit, ok, want)
+		}
+	}
+
+	match = newRateLimitMatcher([]RateLimitRule{{Operation: "/a.Svc/Put", Limit: 1, Period: time.Second}})
+	if _, ok := match("/a.Svc/Get"); ok {
+		t.Fatal("no default rule should not match")
+	}
+}
+
+func TestRateLimitKeyFuncs(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.8"), Port: 5678},
+	})
+	if got := RateLimitByPeer()(ctx); got != "10.0.0.8" {
+		t.Fatalf("peer key=%q", got)
+	}
+
+	tr := newTestTransport("/a.Svc/Get")
+	tr.reqHeader.Set("x-api-key", "k1")
+	ctx = transport.NewServerContext(context.Background(), tr)
+	if got := RateLimitByHeader("x-api-key")(ctx); got != "k1" {
+		t.Fatalf("header key=%q", got)
+	}
+	if got := RateLimitByHeader("x-api-key")(context.Background()); got != "" {
+		t.Fatalf("header key without transport=%q", got)
+	}
+}
+
+func TestRateLimitedError(t *testing.T) {
+	tr := newTestTransport("/a.Svc/Get")
+	err := rateLimitedError(tr, 1500*time.Millisecond)
+
+	se := errors.FromError(err)
+	if se.Code != 429 || se.Reason != ReasonRateLimited {
+		t.Fatalf("code=%d reason=%s", se.Code, se.Reason)
+	}
+	if se.Metadata["retry_after_ms"] != "1500" {
+		t.Fatalf("metadata=%v", se.Metadata)
+	}
+	if got := tr.repHeader.Get("Retry-After"); got != "2" {
+		t.Fatalf("Retry-After=%q", got)
+	}
+}
+
+func TestRateLimiterRedisUnavailable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	rules := []RateLimitRule{{Limit: 1, Period: time.Second}}
+	ctx := transport.NewServerContext(context.Background(), newTestTransport("/a.Svc/Get"))
+	next := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	reply, err := RateLimiter(rdb, rules, WithRateLimitLogger(log.DefaultLogger))(next)(ctx, nil)
+	if err != nil || reply != "ok" {
+		t.Fatalf("fail-open should pass through: reply=%v err=%v", reply, err)
+	}
+
+	_, err = RateLimiter(rdb, rules, WithRateLimitFailOpen(false))(next)(ctx, nil)
+	if se := errors.FromError(err); se == nil || se.Code != 503 || se.Reason != ReasonRateLimiterUnavailable {
+		t.Fatalf("fail-closed should return 503 / %s, got %v", ReasonRateLimiterUnavailable, err)
+	}
+}
@@ -0,0 +1,154 @@
+package kratosx
+
+import (
+	"context"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// RetryPolicy 客户端重试策略，零值字段使用默认值；只重试 Idempotent 中声明的幂等 operation
+type RetryPolicy struct {
+	// MaxAttempts 最大调用次数（含首次），默认 3
+	MaxAttempts int
+	// Idempotent 允许重试的幂等 operation（path.Match 语法，如 /*/Get*），为空时不重试
+	Idempotent []string
+	// Codes 可重试的 kratos code，默认 503（gRPC Unavailable）；熔断打开的 503 不会重试
+	Codes []int
+	// Backoff 首次重试的退避时间，之后按 2 倍递增，默认 50ms
+	Backoff time.Duration
+	// MaxBackoff 退避上限，默认 1s
+	MaxBackoff time.Duration
+	// Jitter 退避时间的随机抖动比例，最大 1，默认 0.2，<0 时不抖动
+	Jitter float64
+	// BudgetRatio 重试预算：每次调用存入 BudgetRatio 个令牌，每次重试消耗 1 个，默认 0.1（重试不超过调用量的约 10%）
+	BudgetRatio float64
+	// BudgetBurst 预算令牌上限（也是初始值），默认 10
+	BudgetBurst float64
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if len(p.Codes) == 0 {
+		p.Codes = []int{503}
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = 50 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Second
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	p.Jitter = min(p.Jitter, 1)
+	if p.BudgetRatio <= 0 {
+		p.BudgetRatio = 0.1
+	}
+	if p.BudgetBurst <= 0 {
+		p.BudgetBurst = 10
+	}
+	return p
+}
+
+func (p RetryPolicy) idempotent(operation string) bool {
+	for _, g := range p.Idempotent {
+		if ok, _ := path.Match(g, operation); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	se := errors.FromError(err)
+	if se == nil || se.Reason == ReasonCircuitOpen {
+		return false
+	}
+	return slices.Contains(p.Codes, int(se.Code))
+}
+
+// backoff 第 attempt 次重试（从 1 开始）前的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff)
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1))) //nolint:gosec
+	}
+	return d
+}
+
+// retryBudget 令牌桶式的重试预算，防止下游故障时重试放大流量
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	burst  float64
+	tokens float64
+}
+
+func newRetryBudget(ratio, burst float64) *retryBudget {
+	return &retryBudget{ratio: ratio, burst: burst, tokens: burst}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	b.tokens = min(b.burst, b.tokens+b.ratio)
+	b.mu.Unlock()
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Retry 客户端重试中间件：仅对幂等 operation、可重试的错误在预算内按指数退避 + 抖动重试；
+// 重试共享调用方的 ctx（含 ConnFactory 的调用超时），ctx 结束后不再重试
+func Retry(policy RetryPolicy) middleware.Middleware {
+	p := policy.withDefaults()
+	budget := newRetryBudget(p.BudgetRatio, p.BudgetBurst)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			budget.deposit()
+
+			var operation string
+			if info, ok := transport.FromClientContext(ctx); ok {
+				operation = info.Operation()
+			}
+			if p.MaxAttempts <= 1 || !p.idempotent(operation) {
+				return handler(ctx, req)
+			}
+
+			reply, err := handler(ctx, req)
+			for attempt := 1; attempt < p.MaxAttempts; attempt++ {
+				if !p.retryable(err) || !budget.withdraw() {
+					break
+				}
+				timer := time.NewTimer(p.backoff(attempt))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return reply, err
+				case <-timer.C:
+				}
+				reply, err = handler(ctx, req)
+			}
+			return reply, err
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package kratosx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 35 * time.Millisecond, Jitter: -1}.withDefaults()
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 35 * time.Millisecond, 10: 35 * time.Millisecond} {
+		if got := p.backoff(attempt); got != want {
+			t.Fatalf("attempt %d backoff=%v want %v", attempt, got, want)
+		}
+	}
+
+	p = RetryPolicy{Backoff: 100 * time.Millisecond, Jitter: 0.5}.withDefaults()
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("jittered backoff=%v", got)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{Idempotent: []string{"/*/Get*"}, Backoff: time.Millisecond, Jitter: -1}
+	unavailable := errors.ServiceUnavailable("DOWN", "down")
+
+	run := func(op string, errs ...error) (int, error) {
+		calls := 0
+		ctx := transport.NewClientContext(context.Background(), newTestTransport(op))
+		_, err := Retry(policy)(func(context.Context, interface{}) (interface{}, error) {
+			calls++
+			if calls <= len(errs) {
+				return nil, errs[calls-1]
+			}
+			return "ok", nil
+		})(ctx, nil)
+		return calls, err
+	}
+
+	if calls, err := run("/order.Svc/Get", unavailable, unavailable); err != nil || calls != 3 {
+		t.Fatalf("idempotent calls=%d err=%v", calls, err)
+	}
+	if calls, err := run("/order.Svc/Get", unavailable, unavailable, unavailable); err == nil || calls != 3 {
+		t.Fatalf("max attempts calls=%d err=%v", calls, err)
+	}
+	if calls, _ := run("/order.Svc/Create", unavailable); calls != 1 {
+		t.Fatalf("non-idempotent calls=%d", calls)
+	}
+	if calls, _ := run("/order.Svc/Get", errors.BadRequest("BAD", "bad")); calls != 1 {
+		t.Fatalf("non-retryable calls=%d", calls)
+	}
+	if calls, _ := run("/order.Svc/Get", errors.ServiceUnavailable(ReasonCircuitOpen, "open")); calls != 1 {
+		t.Fatalf("circuit open calls=%d", calls)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(0.5, 2)
+	if !b.withdraw() || !b.withdraw() || b.withdraw() {
+		t.Fatal("budget should start full and allow burst retries")
+	}
+	b.deposit()
+	if b.withdraw() {
+		t.Fatal("half a token should not allow a retry")
+	}
+	b.deposit()
+	b.deposit()
+	if !b.withdraw() {
+		t.Fatal("deposits should refill the budget")
+	}
+}
@@ -0,0 +1,147 @@
+package kratosx
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+)
+
+// DefaultRouteHeader 固定路由子集的 metadata，x-md-global- 前缀可经 kratos metadata 中间件逐跳透传
+const DefaultRouteHeader = "x-md-global-route"
+
+// NodeMatch 节点匹配条件：Version 为空时不限制版本，Labels 需与实例 metadata 全部相等；零值匹配全部节点
+type NodeMatch struct {
+	Version string
+	Labels  map[string]string
+}
+
+// ParseNodeMatch 解析 "version=v2,env=canary" 形式的匹配条件，version 对应实例版本，其余为 metadata
+func ParseNodeMatch(s string) (NodeMatch, error) {
+	var m NodeMatch
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" {
+			return NodeMatch{}, errors.Errorf("invalid node match %q", kv)
+		}
+		if k == "version" {
+			m.Version = v
+			continue
+		}
+		if m.Labels == nil {
+			m.Labels = make(map[string]string)
+		}
+		m.Labels[k] = v
+	}
+	return m, nil
+}
+
+// IsZero 是否为零值（不做任何过滤）
+func (m NodeMatch) IsZero() bool {
+	return m.Version == "" && len(m.Labels) == 0
+}
+
+func (m NodeMatch) matches(n selector.Node) bool {
+	if m.Version != "" && n.Version() != m.Version {
+		return false
+	}
+	md := n.Metadata()
+	for k, v := range m.Labels {
+		if md[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// partition 按条件拆分为匹配与不匹配的节点
+func (m NodeMatch) partition(nodes []selector.Node) (matched, rest []selector.Node) {
+	for _, n := range nodes {
+		if m.matches(n) {
+			matched = append(matched, n)
+		} else {
+			rest = append(rest, n)
+		}
+	}
+	return matched, rest
+}
+
+// RoutePolicy 基于实例版本 / metadata 的路由策略，任何一步筛选结果为空时回退到筛选前的节点
+type RoutePolicy struct {
+	// Match 基础子集，如 NodeMatch{Labels: {"env": "prod"}}；零值不过滤
+	Match NodeMatch
+	// Canary 灰度子集，如 NodeMatch{Labels: {"env": "canary"}}；零值时不做灰度
+	Canary NodeMatch
+	// CanaryPercent 路由到灰度子集的请求百分比 [0, 100]，其余请求只路由到非灰度节点
+	CanaryPercent float64
+	// PinHeader 请求携带该 metadata / 请求头（值为 ParseNodeMatch 格式）时固定路由到对应子集，
+	// 优先于 Match 与灰度比例；默认 DefaultRouteHeader
+	PinHeader string
+}
+
+// RouteFilter 返回 kratos selector.NodeFilter，可通过 kgrpc.WithNodeFilter 或 ConnFactory 的 WithConnRoute 使用
+func RouteFilter(p RoutePolicy) selector.NodeFilter {
+	if p.PinHeader == "" {
+		p.PinHeader = DefaultRouteHeader
+	}
+	return func(ctx context.Context, nodes []selector.Node) []selector.Node {
+		if pin, ok := routePin(ctx, p.PinHeader); ok {
+			matched, _ := pin.partition(nodes)
+			return orAll(matched, nodes)
+		}
+		if !p.Match.IsZero() {
+			matched, _ := p.Match.partition(nodes)
+			nodes = orAll(matched, nodes)
+		}
+		if p.Canary.IsZero() {
+			return nodes
+		}
+		canary, stable := p.Canary.partition(nodes)
+		if rand.Float64()*100 < p.CanaryPercent { //nolint:gosec
+			return orAll(canary, nodes)
+		}
+		return orAll(stable, nodes)
+	}
+}
+
+// orAll 子集为空时回退到全部节点
+func orAll(subset, all []selector.Node) []selector.Node {
+	if len(subset) == 0 {
+		return all
+	}
+	return subset
+}
+
+// routePin 依次从客户端 metadata、服务端 metadata、服务端请求头读取固定路由条件
+func routePin(ctx context.Context, header string) (NodeMatch, bool) {
+	var v string
+	if md, ok := metadata.FromClientContext(ctx); ok {
+		v = md.Get(header)
+	}
+	if v == "" {
+		if md, ok := metadata.FromServerContext(ctx); ok {
+			v = md.Get(header)
+		}
+	}
+	if v == "" {
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			v = tr.RequestHeader().Get(header)
+		}
+	}
+	if v == "" {
+		return NodeMatch{}, false
+	}
+	m, err := ParseNodeMatch(v)
+	if err != nil || m.IsZero() {
+		return NodeMatch{}, false
+	}
+	return m, true
+}
@@ -0,0 +1,122 @@
+package kratosx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func testNodes() []selector.Node {
+	ins := []*registry.ServiceInstance{
+		{ID: "1", Version: "v1", Metadata: map[string]string{"env": "prod", "zone": "a"}},
+		{ID: "2", Version: "v1", Metadata: map[string]string{"env": "prod", "zone": "b"}},
+		{ID: "3", Version: "v2", Metadata: map[string]string{"env": "canary", "zone": "a"}},
+	}
+	nodes := make([]selector.Node, 0, len(ins))
+	for _, in := range ins {
+		nodes = append(nodes, selector.NewNode("grpc", "127.0.0.1:900"+in.ID, in))
+	}
+	return nodes
+}
+
+func nodeVersions(nodes []selector.Node) map[string]int {
+	out := map[string]int{}
+	for _, n := range nodes {
+		out[n.Version()]++
+	}
+	return out
+}
+
+func TestParseNodeMatch(t *testing.T) {
+	m, err := ParseNodeMatch(" version=v2, env=canary ,")
+	if err != nil || m.Version != "v2" || m.Labels["env"] != "canary" || len(m.Labels) != 1 {
+		t.Fatalf("match=%+v err=%v", m, err)
+	}
+	if _, err := ParseNodeMatch("canary"); err == nil {
+		t.Fatal("missing '=' should fail")
+	}
+	if m, _ := ParseNodeMatch(""); !m.IsZero() {
+		t.Fatal("empty string should be zero match")
+	}
+}
+
+func TestRouteFilterMatchAndFallback(t *testing.T) {
+	ctx := context.Background()
+	nodes := testNodes()
+
+	got := RouteFilter(RoutePolicy{Match: NodeMatch{Labels: map[string]string{"zone": "a"}}})(ctx, nodes)
+	if len(got) != 2 {
+		t.Fatalf("zone=a nodes=%d", len(got))
+	}
+	got = RouteFilter(RoutePolicy{Match: NodeMatch{Version: "v9"}})(ctx, nodes)
+	if len(got) != len(nodes) {
+		t.Fatalf("empty subset should fall back to all nodes, got %d", len(got))
+	}
+}
+
+func TestRouteFilterCanary(t *testing.T) {
+	ctx := context.Background()
+	nodes := testNodes()
+	canary := NodeMatch{Labels: map[string]string{"env": "canary"}}
+
+	if v := nodeVersions(RouteFilter(RoutePolicy{Canary: canary})(ctx, nodes)); v["v2"] != 0 || v["v1"] != 2 {
+		t.Fatalf("0%% canary should only route to stable nodes: %v", v)
+	}
+	if v := nodeVersions(RouteFilter(RoutePolicy{Canary: canary, CanaryPercent: 100})(ctx, nodes)); v["v2"] != 1 || v["v1"] != 0 {
+		t.Fatalf("100%% canary should only route to canary nodes: %v", v)
+	}
+
+	f := RouteFilter(RoutePolicy{Canary: canary, CanaryPercent: 20})
+	hits := 0
+	for i := 0; i < 2000; i++ {
+		if nodeVersions(f(ctx, nodes))["v2"] > 0 {
+			hits++
+		}
+	}
+	if hits < 300 || hits > 500 {
+		t.Fatalf("20%% canary hit %d of 2000", hits)
+	}
+
+	// 没有灰度实例时全部回退到现有节点
+	stableOnly := nodes[:2]
+	if got := RouteFilter(RoutePolicy{Canary: canary, CanaryPercent: 100})(ctx, stableOnly); len(got) != 2 {
+		t.Fatalf("missing canary should fall back, got %d", len(got))
+	}
+}
+
+func TestRouteFilterPin(t *testing.T) {
+	nodes := testNodes()
+	f := RouteFilter(RoutePolicy{Canary: NodeMatch{Labels: map[string]string{"env": "canary"}}})
+
+	ctx := metadata.NewClientContext(context.Background(), metadata.New(map[string][]string{DefaultRouteHeader: {"version=v2"}}))
+	if v := nodeVersions(f(ctx, nodes)); v["v2"] != 1 || v["v1"] != 0 {
+		t.Fatalf("client metadata pin: %v", v)
+	}
+
+	tr := newTestTransport("/order.Svc/Get")
+	tr.reqHeader.Set(DefaultRouteHeader, "env=canary")
+	ctx = transport.NewServerContext(context.Background(), tr)
+	if v := nodeVersions(f(ctx, nodes)); v["v2"] != 1 {
+		t.Fatalf("incoming header pin: %v", v)
+	}
+
+	tr.reqHeader.Set(DefaultRouteHeader, "zone=c")
+	if got := f(ctx, nodes); len(got) != len(nodes) {
+		t.Fatalf("empty pinned subset should fall back to all nodes, got %d", len(got))
+	}
+}
+
+func TestConnRouteOption(t *testing.T) {
+	o := defaultConnOptions()
+	WithConnRoute(RoutePolicy{Match: NodeMatch{Version: "v1"}})(&o)
+	if len(o.nodeFilters) != 1 {
+		t.Fatalf("node filters=%d", len(o.nodeFilters))
+	}
+	if len(o.clone().nodeFilters) != 1 {
+		t.Fatal("clone should copy node filters")
+	}
+}
@@ -14,10 +14,11 @@ import (
 	"google.golang.org/grpc/peer"
 )
 
-const MaxShowBodyLen = 1024
-
+// ServerLogger 服务端请求日志；脱敏、采样、跳过与截断规则见 LogPolicy
+//
 //nolint:funlen
-func ServerLogger(logger log.Logger) middleware.Middleware {
+func ServerLogger(logger log.Logger, opts ...LoggerOption) middleware.Middleware {
+	policy := newLogPolicy(opts...)
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
 			// INFO[22945]                                               args="task_id:\"66bf4df37d164f82a6501f0f623c9eae\"" caller="validate.go:23" code=0 component=grpc
@@ -36,22 +37,32 @@ func ServerLogger(logger log.Logger) middleware.Middleware {
 				kind = info.Kind().String()
 				operation = info.Operation()
 			}
+			if policy.skip(operation) {
+				return handler(ctx, req)
+			}
 
 			// 获取来源 IP
 			if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
 				clientIP = p.Addr.String()
 			}
 
-			_ = log.WithContext(ctx, logger).Log(log.LevelInfo,
-				"kind", "server",
-				"component", kind,
-				"operation", operation,
-				"type", "New Request",
-				"client_ip", clientIP,
-				"msg", TruncateBytes(extractArgs(req), MaxShowBodyLen),
-			)
+			// 未被采样时不输出 New Request，若请求失败则在 Request Done 中补充请求体
+			sampled := policy.sampled()
+			if sampled {
+				_ = log.WithContext(ctx, logger).Log(log.LevelInfo,
+					"kind", "server",
+					"component", kind,
+					"operation", operation,
+					"type", "New Request",
+					"client_ip", clientIP,
+					"msg", policy.body(operation, req),
+				)
+			}
 
 			reply, err = handler(ctx, req)
+			if err == nil && !sampled {
+				return
+			}
 			if se := errors.FromError(err); se != nil {
 				code = se.Code
 				reason = se.Reason
@@ -70,7 +81,7 @@ func ServerLogger(logger log.Logger) middleware.Middleware {
 				)
 			}
 
-			_ = log.WithContext(ctx, logger).Log(level,
+			keyvals := []interface{}{
 				"kind", "server",
 				"component", kind,
 				"operation", operation,
@@ -79,8 +90,12 @@ func ServerLogger(logger log.Logger) middleware.Middleware {
 				"stack", stack,
 				"latency", time.Since(startTime).Seconds(),
 				"type", "Request Done",
-				"msg", TruncateBytes(extractArgs(reply), MaxShowBodyLen),
-			)
+				"msg", policy.body(operation, reply),
+			}
+			if !sampled {
+				keyvals = append(keyvals, "client_ip", clientIP, "args", policy.body(operation, req))
+			}
+			_ = log.WithContext(ctx, logger).Log(level, keyvals...)
 			return
 		}
 	}
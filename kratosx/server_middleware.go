@@ -0,0 +1,172 @@
+package kratosx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	pkgErr "github.com/pkg/errors"
+)
+
+// ReasonPanic panic 被恢复后返回的 error reason
+const ReasonPanic = "PANIC"
+
+// DefaultRequestIDHeader 请求 id 的请求头 / metadata
+const DefaultRequestIDHeader = "x-request-id"
+
+type requestIDCtxKey struct{}
+
+// OperationDeadline 按 operation 设置的最长处理时间
+type OperationDeadline struct {
+	Operation string // path.Match 语法，如 /pkg.Service/*
+	Timeout   time.Duration
+}
+
+type serverMiddlewareOptions struct {
+	requestIDHeader string
+	defaultDeadline time.Duration
+	deadlines       []OperationDeadline
+	logOpts         []LoggerOption
+	extra           []middleware.Middleware
+}
+
+// ServerMiddlewareOption DefaultServerMiddleware 的可选参数
+type ServerMiddlewareOption func(o *serverMiddlewareOptions)
+
+// WithRequestIDHeader 请求 id 所在的请求头，默认 x-request-id
+func WithRequestIDHeader(header string) ServerMiddlewareOption {
+	return func(o *serverMiddlewareOptions) { o.requestIDHeader = header }
+}
+
+// WithDefaultDeadline 所有 operation 的最长处理时间，<=0 时不限制（默认）
+func WithDefaultDeadline(d time.Duration) ServerMiddlewareOption {
+	return func(o *serverMiddlewareOptions) { o.defaultDeadline = d }
+}
+
+// WithOperationDeadline 按 operation 覆盖最长处理时间，按添加顺序取第一个匹配项
+func WithOperationDeadline(operation string, d time.Duration) ServerMiddlewareOption {
+	return func(o *serverMiddlewareOptions) {
+		o.deadlines = append(o.deadlines, OperationDeadline{Operation: operation, Timeout: d})
+	}
+}
+
+// WithServerLogOptions ServerLogger 的可选参数（如 WithLogPolicy）
+func WithServerLogOptions(opts ...LoggerOption) ServerMiddlewareOption {
+	return func(o *serverMiddlewareOptions) { o.logOpts = append(o.logOpts, opts...) }
+}
+
+// WithServerMiddleware 追加业务中间件，位于套件内层（在 deadline 之后、handler 之前执行）
+func WithServerMiddleware(ms ...middleware.Middleware) ServerMiddlewareOption {
+	return func(o *serverMiddlewareOptions) { o.extra = append(o.extra, ms...) }
+}
+
+// DefaultServerMiddleware 服务端通用中间件套件，执行顺序：
+// RequestID → ServerLogger（日志带 request_id）→ Recovery → Deadline → 业务中间件
+func DefaultServerMiddleware(logger log.Logger, opts ...ServerMiddlewareOption) middleware.Middleware {
+	o := serverMiddlewareOptions{requestIDHeader: DefaultRequestIDHeader}
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	ms := []middleware.Middleware{
+		RequestID(o.requestIDHeader),
+		ServerLogger(log.With(logger, "request_id", RequestIDValuer()), o.logOpts...),
+		Recovery(),
+		Deadline(o.defaultDeadline, o.deadlines...),
+	}
+	return middleware.Chain(append(ms, o.extra...)...)
+}
+
+// Recovery 将 panic 转为 500 / PANIC 错误，cause 携带 panic 处的堆栈，可被 ServerLogger 输出
+func Recovery() middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					// 在 defer 中取堆栈，此时尚未展开，堆栈包含 panic 发生处
+					cause := pkgErr.WithStack(fmt.Errorf("panic: %v", r))
+					err = errors.InternalServer(ReasonPanic, "internal server error").WithCause(cause)
+				}
+			}()
+			return handler(ctx, req)
+		}
+	}
+}
+
+// RequestID 从请求头读取请求 id，缺失时生成；写入 ctx 与响应头
+func RequestID(header string) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var id string
+			if info, ok := transport.FromServerContext(ctx); ok {
+				if id = info.RequestHeader().Get(header); id == "" {
+					id = newRequestID()
+				}
+				info.ReplyHeader().Set(header, id)
+			} else {
+				id = newRequestID()
+			}
+			return handler(context.WithValue(ctx, requestIDCtxKey{}, id), req)
+		}
+	}
+}
+
+// RequestIDFromContext 返回 RequestID 中间件写入的请求 id
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// RequestIDValuer 日志字段，配合 log.With(logger, "request_id", RequestIDValuer()) 使用
+func RequestIDValuer() log.Valuer {
+	return func(ctx context.Context) interface{} {
+		if ctx == nil {
+			return ""
+		}
+		return RequestIDFromContext(ctx)
+	}
+}
+
+// Deadline 限制处理时间：ctx 没有 deadline 或 deadline 晚于上限时收紧到上限，早于上限时保持调用方的 deadline
+func Deadline(defaultTimeout time.Duration, perOperation ...OperationDeadline) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var operation string
+			if info, ok := transport.FromServerContext(ctx); ok {
+				operation = info.Operation()
+			}
+			limit := operationTimeout(operation, defaultTimeout, perOperation)
+			if limit <= 0 {
+				return handler(ctx, req)
+			}
+			if dl, ok := ctx.Deadline(); ok && time.Until(dl) <= limit {
+				return handler(ctx, req)
+			}
+			ctx, cancel := context.WithTimeout(ctx, limit)
+			defer cancel()
+			return handler(ctx, req)
+		}
+	}
+}
+
+func operationTimeout(operation string, def time.Duration, perOperation []OperationDeadline) time.Duration {
+	for _, d := range perOperation {
+		if ok, _ := path.Match(d.Operation, operation); ok {
+			return d.Timeout
+		}
+	}
+	return def
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
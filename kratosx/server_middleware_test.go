@@ -0,0 +1,113 @@
+package kratosx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestRecovery(t *testing.T) {
+	_, err := Recovery()(func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})(context.Background(), nil)
+
+	se := errors.FromError(err)
+	if se == nil || se.Code != 500 || se.Reason != ReasonPanic {
+		t.Fatalf("err=%v", err)
+	}
+	if cause := se.Unwrap(); cause == nil || !strings.Contains(cause.Error(), "boom") {
+		t.Fatalf("cause=%v", cause)
+	}
+	stack := fmt.Sprintf("%+v", getErrCauseStack(err))
+	if !strings.Contains(stack, "TestRecovery.func1") {
+		t.Fatalf("stack should point to the panic site: %s", stack)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := RequestID(DefaultRequestIDHeader)(func(ctx context.Context, _ interface{}) (interface{}, error) {
+		got = RequestIDFromContext(ctx)
+		return nil, nil
+	})
+
+	tr := newTestTransport("/order.Svc/Get")
+	tr.reqHeader.Set(DefaultRequestIDHeader, "abc")
+	_, _ = h(transport.NewServerContext(context.Background(), tr), nil)
+	if got != "abc" || tr.repHeader.Get(DefaultRequestIDHeader) != "abc" {
+		t.Fatalf("propagated id=%q reply=%q", got, tr.repHeader.Get(DefaultRequestIDHeader))
+	}
+
+	tr = newTestTransport("/order.Svc/Get")
+	_, _ = h(transport.NewServerContext(context.Background(), tr), nil)
+	if len(got) != 32 || tr.repHeader.Get(DefaultRequestIDHeader) != got {
+		t.Fatalf("generated id=%q reply=%q", got, tr.repHeader.Get(DefaultRequestIDHeader))
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	var remaining time.Duration
+	h := Deadline(time.Minute, OperationDeadline{Operation: "/report.Svc/*", Timeout: time.Hour})(
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			dl, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("deadline not set")
+			}
+			remaining = time.Until(dl)
+			return nil, nil
+		})
+
+	ctx := transport.NewServerContext(context.Background(), newTestTransport("/order.Svc/Get"))
+	_, _ = h(ctx, nil)
+	if remaining > time.Minute || remaining < 50*time.Second {
+		t.Fatalf("default cap remaining=%v", remaining)
+	}
+
+	ctx = transport.NewServerContext(context.Background(), newTestTransport("/report.Svc/Export"))
+	_, _ = h(ctx, nil)
+	if remaining <= time.Minute {
+		t.Fatalf("per-operation cap remaining=%v", remaining)
+	}
+
+	// 调用方的 deadline 更早时保持不变
+	short, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	_, _ = h(short, nil)
+	if remaining > time.Second {
+		t.Fatalf("caller deadline remaining=%v", remaining)
+	}
+}
+
+func TestDefaultServerMiddleware(t *testing.T) {
+	logger := &recordLogger{}
+	tr := newTestTransport("/order.Svc/Get")
+	tr.reqHeader.Set(DefaultRequestIDHeader, "req-1")
+
+	m := DefaultServerMiddleware(logger, WithDefaultDeadline(time.Second))
+	_, err := m(func(ctx context.Context, _ interface{}) (interface{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("deadline not applied")
+		}
+		panic("boom")
+	})(transport.NewServerContext(context.Background(), tr), nil)
+
+	if se := errors.FromError(err); se == nil || se.Reason != ReasonPanic {
+		t.Fatalf("err=%v", err)
+	}
+	if len(logger.lines) == 0 {
+		t.Fatal("no log lines")
+	}
+	for _, line := range logger.lines {
+		if !strings.Contains(line, "request_idreq-1") {
+			t.Fatalf("line missing request id: %q", line)
+		}
+	}
+	if !strings.Contains(strings.Join(logger.lines, "\n"), "Error Stack") {
+		t.Fatalf("panic stack not logged: %v", logger.lines)
+	}
+}
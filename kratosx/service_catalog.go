@@ -0,0 +1,187 @@
+package kratosx
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+)
+
+// ReasonMetadataRequired 调用缺少服务目录要求的 metadata 时返回的 error reason（400）
+const ReasonMetadataRequired = "METADATA_REQUIRED"
+
+// ErrServiceNotInCatalog 服务未在服务目录中登记
+var ErrServiceNotInCatalog = errors.New("service not in catalog")
+
+// Protocol 服务对外提供的协议，同时是注册中心中服务名的后缀（见 GrpcServiceName / HTTPServiceName）
+type Protocol string
+
+const (
+	ProtocolGRPC Protocol = "grpc"
+	ProtocolHTTP Protocol = "http"
+)
+
+var serviceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ServiceSpec 服务目录中的一项
+type ServiceSpec struct {
+	Name      ServiceName
+	Protocols []Protocol // 为空时为 [grpc]
+	// Timeout 单次调用的默认超时，0 时使用 ConnFactory 的全局设置
+	Timeout time.Duration
+	// RequiredMetadata 调用该服务时必须携带的 metadata（如 x-md-global-tenant），缺失时调用方直接返回 400
+	RequiredMetadata []string
+}
+
+// Serves 是否提供 p 协议
+func (s ServiceSpec) Serves(p Protocol) bool {
+	return slices.Contains(s.Protocols, p)
+}
+
+// ServiceCatalog 应用依赖的下游服务目录，创建后只读；
+// ConnFactory 通过 WithConnCatalog 使用目录解析服务名，未登记的服务在启动时报错
+type ServiceCatalog struct {
+	specs map[ServiceName]ServiceSpec
+}
+
+// NewServiceCatalog 校验并创建服务目录：名称不能为空、不能重复，协议只能为 grpc / http
+func NewServiceCatalog(specs ...ServiceSpec) (*ServiceCatalog, error) {
+	c := &ServiceCatalog{specs: make(map[ServiceName]ServiceSpec, len(specs))}
+	for _, s := range specs {
+		if !serviceNamePattern.MatchString(s.Name) {
+			return nil, errors.Errorf("invalid service name %q", s.Name)
+		}
+		if _, ok := c.specs[s.Name]; ok {
+			return nil, errors.Errorf("duplicate service %q", s.Name)
+		}
+		if len(s.Protocols) == 0 {
+			s.Protocols = []Protocol{ProtocolGRPC}
+		}
+		for _, p := range s.Protocols {
+			if p != ProtocolGRPC && p != ProtocolHTTP {
+				return nil, errors.Errorf("service %q: unknown protocol %q", s.Name, p)
+			}
+		}
+		if s.Timeout < 0 {
+			return nil, errors.Errorf("service %q: negative timeout", s.Name)
+		}
+		s.Protocols = slices.Clone(s.Protocols)
+		s.RequiredMetadata = slices.Clone(s.RequiredMetadata)
+		c.specs[s.Name] = s
+	}
+	return c, nil
+}
+
+// ServiceSpecConf 配置文件中的服务目录项，如
+//
+//	services:
+//	  - name: order
+//	    protocols: [grpc]
+//	    timeout: 5s
+//	    required_metadata: [x-md-global-tenant]
+type ServiceSpecConf struct {
+	Name             string   `json:"name"`
+	Protocols        []string `json:"protocols"`
+	Timeout          string   `json:"timeout"` // time.ParseDuration 格式
+	RequiredMetadata []string `json:"required_metadata"`
+}
+
+// LoadServiceCatalog 从 kratos 配置的 key 处读取服务目录；
+// 配置可来自本地文件或 Nacos 配置中心（nacosx.NewConfigSource）
+func LoadServiceCatalog(c config.Config, key string) (*ServiceCatalog, error) {
+	var confs []ServiceSpecConf
+	if err := c.Value(key).Scan(&confs); err != nil {
+		return nil, errors.WithMessagef(err, "scan service catalog %s", key)
+	}
+
+	specs := make([]ServiceSpec, 0, len(confs))
+	for _, sc := range confs {
+		spec := ServiceSpec{Name: sc.Name, RequiredMetadata: sc.RequiredMetadata}
+		for _, p := range sc.Protocols {
+			spec.Protocols = append(spec.Protocols, Protocol(p))
+		}
+		if sc.Timeout != "" {
+			d, err := time.ParseDuration(sc.Timeout)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "service %q timeout", sc.Name)
+			}
+			spec.Timeout = d
+		}
+		specs = append(specs, spec)
+	}
+	return NewServiceCatalog(specs...)
+}
+
+// Lookup 返回服务的登记信息，未登记时返回 ErrServiceNotInCatalog
+func (c *ServiceCatalog) Lookup(name ServiceName) (ServiceSpec, error) {
+	s, ok := c.specs[name]
+	if !ok {
+		return ServiceSpec{}, errors.WithMessagef(ErrServiceNotInCatalog, "%q", name)
+	}
+	return s, nil
+}
+
+// DiscoveryName 服务在注册中心中的名称，如 order -> order.grpc（与 nacosx.Registry 注册时的命名一致）；
+// 启用 TLS 的 gRPC 服务注册为 order.grpcs，由 ConnFactory 按连接参数选择
+func (c *ServiceCatalog) DiscoveryName(name ServiceName, p Protocol) (string, error) {
+	s, err := c.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if !s.Serves(p) {
+		return "", errors.Errorf("service %q does not serve %s", name, p)
+	}
+	if p == ProtocolHTTP {
+		return HTTPServiceName(name), nil
+	}
+	return GrpcServiceName(name), nil
+}
+
+// Names 返回全部服务名（已排序）
+func (c *ServiceCatalog) Names() []ServiceName {
+	names := make([]ServiceName, 0, len(c.specs))
+	for n := range c.specs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// connOptions 目录项对应的连接参数，在 WithServiceConnOptions 之前应用
+func (s ServiceSpec) connOptions() []ConnOption {
+	var opts []ConnOption
+	if s.Timeout > 0 {
+		opts = append(opts, WithConnTimeout(s.Timeout))
+	}
+	if len(s.RequiredMetadata) > 0 {
+		opts = append(opts, WithConnMiddleware(requireMetadata(s.Name, s.RequiredMetadata)))
+	}
+	return opts
+}
+
+// requireMetadata 校验调用携带了必需的 metadata（kratos metadata 或请求头）
+func requireMetadata(service ServiceName, keys []string) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			md, _ := metadata.FromClientContext(ctx)
+			info, hasTr := transport.FromClientContext(ctx)
+			for _, k := range keys {
+				if md.Get(k) != "" || (hasTr && info.RequestHeader().Get(k) != "") {
+					continue
+				}
+				return nil, kerrors.BadRequest(ReasonMetadataRequired,
+					fmt.Sprintf("calling %s requires metadata %s", service, k))
+			}
+			return handler(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package kratosx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/config/file"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/transport"
+	pkgErr "github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNewServiceCatalog(t *testing.T) {
+	c, err := NewServiceCatalog(
+		ServiceSpec{Name: "order"},
+		ServiceSpec{Name: "gateway", Protocols: []Protocol{ProtocolHTTP}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.DiscoveryName("order", ProtocolGRPC); err != nil || got != "order.grpc" {
+		t.Fatalf("order discovery name=%q err=%v", got, err)
+	}
+	if _, err := c.DiscoveryName("gateway", ProtocolGRPC); err == nil {
+		t.Fatal("http-only service should not resolve grpc")
+	}
+	if _, err := c.Lookup("ordr"); !pkgErr.Is(err, ErrServiceNotInCatalog) {
+		t.Fatalf("misspelled name err=%v", err)
+	}
+
+	for _, bad := range [][]ServiceSpec{
+		{{Name: ""}},
+		{{Name: "a b"}},
+		{{Name: "order"}, {Name: "order"}},
+		{{Name: "order", Protocols: []Protocol{"tcp"}}},
+	} {
+		if _, err := NewServiceCatalog(bad...); err == nil {
+			t.Fatalf("specs %+v should be rejected", bad)
+		}
+	}
+}
+
+func TestLoadServiceCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	data := `{"catalog":[
+		{"name":"order","timeout":"5s","required_metadata":["x-md-global-tenant"]},
+		{"name":"gateway","protocols":["http"]}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New(config.WithSource(file.NewSource(path)))
+	defer func() { _ = cfg.Close() }()
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadServiceCatalog(cfg, "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := c.Lookup("order")
+	if err != nil || order.Timeout != 5*time.Second || !order.Serves(ProtocolGRPC) || len(order.RequiredMetadata) != 1 {
+		t.Fatalf("order=%+v err=%v", order, err)
+	}
+	if names := c.Names(); len(names) != 2 || names[0] != "gateway" {
+		t.Fatalf("names=%v", names)
+	}
+}
+
+func TestRequireMetadata(t *testing.T) {
+	h := requireMetadata("order", []string{"x-md-global-tenant"})(func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	ctx := transport.NewClientContext(context.Background(), newTestTransport("/order.Svc/Get"))
+
+	_, err := h(ctx, nil)
+	if se := errors.FromError(err); se.Code != 400 || se.Reason != ReasonMetadataRequired {
+		t.Fatalf("err=%v", err)
+	}
+	if _, err := h(metadata.AppendToClientContext(ctx, "x-md-global-tenant", "t1"), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnFactoryCatalog(t *testing.T) {
+	c, err := NewServiceCatalog(ServiceSpec{Name: "order", Timeout: 7 * time.Second, RequiredMetadata: []string{"x-md-global-tenant"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := NewConnFactory(context.Background(), nil, WithConnCatalog(c),
+		WithServiceConnOptions("ordr", WithConnTimeout(time.Second))); !pkgErr.Is(err, ErrServiceNotInCatalog) {
+		t.Fatalf("misspelled override err=%v", err)
+	}
+
+	f, cleanup, err := NewConnFactory(context.Background(), nil, WithConnCatalog(c), WithConnLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	o := f.optionsFor("order")
+	if o.timeout != 7*time.Second || len(o.middleware) != 1 {
+		t.Fatalf("catalog options not applied: %+v", o)
+	}
+	if target, err := f.target("order"); err != nil || target != "order.grpc" {
+		t.Fatalf("target=%q err=%v", target, err)
+	}
+	if _, err := f.Conn(context.Background(), "billing"); !pkgErr.Is(err, ErrServiceNotInCatalog) {
+		t.Fatalf("unknown service err=%v", err)
+	}
+}
+
+// namedDiscovery 按注册中心中的服务名返回实例
+type namedDiscovery map[string][]*registry.ServiceInstance
+
+func (d namedDiscovery) GetService(_ context.Context, name string) ([]*registry.ServiceInstance, error) {
+	return d[name], nil
+}
+
+func (d namedDiscovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return staticDiscovery{instances: d[name]}.Watch(ctx, name)
+}
+
+func TestConnFactoryCatalogTLS(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	// kratos discovery resolver 以实例名作为 TLS 校验的 ServerName
+	certPEM, keyPEM := ca.issue(t, "order", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})))
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	// 与 nacosx.Registry 一致：TLS 服务以 <name>.grpcs 注册
+	d := namedDiscovery{
+		"order.grpcs": {{ID: "1", Name: "order", Endpoints: []string{"grpcs://" + lis.Addr().String()}}},
+	}
+	c, err := NewServiceCatalog(ServiceSpec{Name: "order"}, ServiceSpec{Name: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, cleanup, err := NewConnFactory(context.Background(), d,
+		WithConnCatalog(c),
+		WithConnLogger(nil),
+		WithServiceConnOptions("order", WithConnTLS(&ClientTLS{CAFile: writeFile(t, t.TempDir(), "ca.pem", ca.pem)})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	if target, err := f.target("order"); err != nil || target != "order.grpcs" {
+		t.Fatalf("tls target=%q err=%v", target, err)
+	}
+	if target, err := f.target("billing"); err != nil || target != "billing.grpc" {
+		t.Fatalf("plaintext target=%q err=%v", target, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := f.Warmup(ctx, "order"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,23 +2,21 @@ package kratosx
 
 import "fmt"
 
+// ServiceName 逻辑服务名（不含协议后缀），应用依赖的服务通过 ServiceCatalog 登记
 type ServiceName = string
 type ServiceID string
 
-const (
-	ServiceNameAppLayout ServiceName = "app_layout" // 项目模板
-
-	ServiceNameProbeApi ServiceName = "probe-api" // 可用性探测服务
-
-	ServiceNameProbeCenter ServiceName = "probe_center" // 控制面服务
-
-	ServiceNameProbeRegin ServiceName = "probe_region" // 控制面服务
-)
-
+// GrpcServiceName 注册中心中 gRPC 服务的名称，nacosx.Registry 注册时按 endpoint 协议追加后缀
 func GrpcServiceName(name ServiceName) string {
 	return fmt.Sprintf("%s.%s", name, "grpc")
 }
 
+// GrpcsServiceName 注册中心中 gRPC TLS 服务的名称（endpoint 协议为 grpcs）
+func GrpcsServiceName(name ServiceName) string {
+	return fmt.Sprintf("%s.%s", name, "grpcs")
+}
+
+// HTTPServiceName 注册中心中 HTTP 服务的名称
 func HTTPServiceName(name ServiceName) string {
 	return fmt.Sprintf("%s.%s", name, "http")
 }
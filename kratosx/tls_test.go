@@ -0,0 +1,219 @@
+package kratosx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, cn string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发叶子证书，返回 cert / key 的 PEM
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// handshake 启动 mTLS 服务端并用 client 配置握手一次，返回服务端看到的客户端证书 CN
+func handshake(t *testing.T, serverConf, clientConf *tls.Config) (string, error) {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cnCh := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			cnCh <- ""
+			return
+		}
+		defer conn.Close()
+		tc := conn.(*tls.Conn)
+		if tc.Handshake() != nil || len(tc.ConnectionState().PeerCertificates) == 0 {
+			cnCh <- ""
+			return
+		}
+		cnCh <- tc.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientConf)
+	if err != nil {
+		return "", err
+	}
+	// TLS 1.3 下客户端证书在首次读写时才被服务端校验
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, _ = conn.Write([]byte("x"))
+	_ = conn.Close()
+	return <-cnCh, nil
+}
+
+func TestClientTLSValidation(t *testing.T) {
+	if _, err := (&ClientTLS{CertFile: "a.pem"}).Config(); err == nil {
+		t.Fatal("cert without key should fail")
+	}
+	if _, err := (&ClientTLS{CAFile: "/not/exist.pem"}).Config(); err == nil {
+		t.Fatal("missing ca file should fail")
+	}
+	dir := t.TempDir()
+	bad := writeFile(t, dir, "bad.pem", []byte("not a pem"))
+	if _, err := (&ClientTLS{CAFile: bad}).Config(); err == nil {
+		t.Fatal("invalid ca pem should fail")
+	}
+}
+
+func TestClientTLSMutualHandshakeAndReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	srvCertPEM, srvKeyPEM := ca.issue(t, "svc.local", x509.ExtKeyUsageServerAuth)
+	srvCert, err := tls.X509KeyPair(srvCertPEM, srvKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPool := x509.NewCertPool()
+	clientPool.AddCert(ca.cert)
+	serverConf := &tls.Config{
+		Certificates: []tls.Certificate{srvCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientPool,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	cliCertPEM, cliKeyPEM := ca.issue(t, "client-1", x509.ExtKeyUsageClientAuth)
+	conf := &ClientTLS{
+		CAFile:         writeFile(t, dir, "ca.pem", ca.pem),
+		CertFile:       writeFile(t, dir, "client.pem", cliCertPEM),
+		KeyFile:        writeFile(t, dir, "client.key", cliKeyPEM),
+		ServerName:     "svc.local",
+		ReloadInterval: time.Millisecond,
+	}
+	clientConf, err := conf.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cn, err := handshake(t, serverConf, clientConf)
+	if err != nil || cn != "client-1" {
+		t.Fatalf("cn=%q err=%v", cn, err)
+	}
+
+	// 轮转客户端证书：文件更新后下一次握手使用新证书
+	cliCertPEM, cliKeyPEM = ca.issue(t, "client-2", x509.ExtKeyUsageClientAuth)
+	writeFile(t, dir, "client.pem", cliCertPEM)
+	writeFile(t, dir, "client.key", cliKeyPEM)
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{conf.CertFile, conf.KeyFile} {
+		if err := os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	cn, err = handshake(t, serverConf, clientConf)
+	if err != nil || cn != "client-2" {
+		t.Fatalf("after reload cn=%q err=%v", cn, err)
+	}
+}
+
+func TestClientTLSRejectsUnknownServer(t *testing.T) {
+	dir := t.TempDir()
+	trusted := newTestCA(t, "trusted-ca")
+	other := newTestCA(t, "other-ca")
+	srvCertPEM, srvKeyPEM := other.issue(t, "svc.local", x509.ExtKeyUsageServerAuth)
+	srvCert, err := tls.X509KeyPair(srvCertPEM, srvKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConf := &tls.Config{Certificates: []tls.Certificate{srvCert}, MinVersion: tls.VersionTLS12}
+
+	for _, reload := range []time.Duration{0, time.Second} {
+		conf := &ClientTLS{
+			CAFile:         writeFile(t, dir, "ca.pem", trusted.pem),
+			ServerName:     "svc.local",
+			ReloadInterval: reload,
+		}
+		clientConf, err := conf.Config()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := handshake(t, serverConf, clientConf); err == nil {
+			t.Fatalf("reload=%s: server signed by unknown ca should be rejected", reload)
+		}
+	}
+}
+
+func TestNewConnFactoryInvalidTLS(t *testing.T) {
+	_, _, err := NewConnFactory(t.Context(), nil, WithConnTLS(&ClientTLS{CAFile: "/not/exist.pem"}))
+	if err == nil {
+		t.Fatal("invalid tls config should fail")
+	}
+}
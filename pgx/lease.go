@@ -0,0 +1,120 @@
+package pgx
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/jeffinity/singularity/friendly"
+)
+
+// 租约 SQL：单语句完成“检查 + 修改”，由行锁保证并发安全
+const (
+	leaseAcquireSQL = `
+INSERT INTO leader_leases (key, holder, epoch, expires_at, successor, successor_until)
+VALUES (@key, @id, 1, now() + @ttl * interval '1 millisecond', '', NULL)
+ON CONFLICT (key) DO UPDATE
+SET holder = EXCLUDED.holder,
+    epoch = leader_leases.epoch + 1,
+    expires_at = EXCLUDED.expires_at,
+    successor = '',
+    successor_until = NULL
+WHERE leader_leases.expires_at <= now()
+  AND (leader_leases.successor = ''
+       OR leader_leases.successor = EXCLUDED.holder
+       OR leader_leases.successor_until IS NULL
+       OR leader_leases.successor_until <= now())
+RETURNING epoch`
+	leaseRenewSQL = `
+UPDATE leader_leases
+SET expires_at = now() + @ttl * interval '1 millisecond'
+WHERE key = @key AND holder = @id AND expires_at > now()`
+	leaseReleaseSQL = `
+UPDATE leader_leases
+SET expires_at = now(),
+    successor = @successor,
+    successor_until = now() + @hint * interval '1 millisecond'
+WHERE key = @key AND holder = @id`
+	leaseHolderSQL = `SELECT holder FROM leader_leases WHERE key = ? AND expires_at > now()`
+)
+
+var _ friendly.LeaseStore = (*LeaseStore)(nil)
+
+// LeaseRecord 租约表，一个选举 key 一行；行不删除，epoch 在其上持续递增
+type LeaseRecord struct {
+	Key            string    `gorm:"primarykey;size:255"`
+	Holder         string    `gorm:"not null;default:''"`
+	Epoch          int64     `gorm:"not null;default:0"`
+	ExpiresAt      time.Time `gorm:"not null"`
+	Successor      string    `gorm:"not null;default:''"`
+	SuccessorUntil *time.Time
+}
+
+func (LeaseRecord) TableName() string {
+	return "leader_leases"
+}
+
+// LeaseStore 基于 PostgreSQL 租约表的 friendly.LeaseStore，可直接使用 NewPostgres 返回的 *gorm.DB。
+// 不使用 advisory lock：其与数据库会话绑定，连接池下无法保证续期/释放落在同一连接上；
+// 改为单条 UPSERT/UPDATE 语句以行锁保证原子性，过期时间统一以数据库 now() 为准，避免实例间时钟偏差。
+type LeaseStore struct {
+	db *gorm.DB
+}
+
+func NewLeaseStore(db *gorm.DB) *LeaseStore {
+	return &LeaseStore{db: db}
+}
+
+// Migrate 创建 / 更新租约表
+func (s *LeaseStore) Migrate(ctx context.Context) error {
+	return errors.WithStack(s.db.WithContext(ctx).AutoMigrate(&LeaseRecord{}))
+}
+
+// TryAcquire 抢占成功时返回新的 epoch（>0），未抢到返回 0
+func (s *LeaseStore) TryAcquire(ctx context.Context, key, id string, ttl time.Duration) (int64, error) {
+	var epochs []int64
+	err := s.db.WithContext(ctx).
+		Raw(leaseAcquireSQL, map[string]any{"key": key, "id": id, "ttl": ttl.Milliseconds()}).
+		Scan(&epochs).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if len(epochs) == 0 {
+		return 0, nil
+	}
+	return epochs[0], nil
+}
+
+func (s *LeaseStore) Renew(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
+	res := s.db.WithContext(ctx).Exec(leaseRenewSQL,
+		map[string]any{"key": key, "id": id, "ttl": ttl.Milliseconds()},
+	)
+	if res.Error != nil {
+		return false, errors.WithStack(res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// Release 将租约置为过期（保留行以延续 epoch）；指定 Successor 时同时写入继任者提示
+func (s *LeaseStore) Release(ctx context.Context, key, id string, opt friendly.ReleaseOptions) error {
+	err := s.db.WithContext(ctx).Exec(leaseReleaseSQL,
+		map[string]any{"key": key, "id": id, "successor": opt.Successor, "hint": opt.HintTTL.Milliseconds()},
+	).Error
+	return errors.WithStack(err)
+}
+
+func (s *LeaseStore) Holder(ctx context.Context, key string) (string, error) {
+	var holders []string
+	err := s.db.WithContext(ctx).
+		Raw(leaseHolderSQL, key).
+		Scan(&holders).Error
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(holders) == 0 {
+		return "", nil
+	}
+	return holders[0], nil
+}
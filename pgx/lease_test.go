@@ -0,0 +1,59 @@
+package pgx
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/jeffinity/singularity/friendly"
+)
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open dry-run db failed: %v", err)
+	}
+	return db
+}
+
+func TestLeaseRecordTableName(t *testing.T) {
+	if got := (LeaseRecord{}).TableName(); got != "leader_leases" {
+		t.Fatalf("unexpected table name: %s", got)
+	}
+}
+
+func TestLeaseSQLBinding(t *testing.T) {
+	db := newDryRunDB(t)
+	args := map[string]any{"key": "job", "id": "a", "ttl": int64(1000), "successor": "b", "hint": int64(500)}
+
+	cases := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{name: "acquire", sql: leaseAcquireSQL, want: []string{"'job'", "'a'", "1000 * interval"}},
+		{name: "renew", sql: leaseRenewSQL, want: []string{"key = 'job'", "holder = 'a'"}},
+		{name: "release", sql: leaseReleaseSQL, want: []string{"successor = 'b'", "500 * interval"}},
+	}
+	for _, c := range cases {
+		got := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return tx.Exec(c.sql, args)
+		})
+		for _, w := range c.want {
+			if !strings.Contains(got, w) {
+				t.Fatalf("%s: expected %q in %s", c.name, w, got)
+			}
+		}
+	}
+}
+
+func TestLeaseStoreImplementsFriendly(t *testing.T) {
+	var _ friendly.LeaseStore = NewLeaseStore(newDryRunDB(t))
+}